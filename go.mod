@@ -1,3 +1,5 @@
 module github.com/f01c33/rpn
 
 go 1.14
+
+require github.com/chzyer/readline v1.5.1
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f01c33/rpn/pkg/rpn"
+)
+
+func TestMain(m *testing.M) {
+	// the defaults of the flags, which aren't parsed
+	output = "text"
+	os.Exit(m.Run())
+}
+
+// tempIn returns a file holding text, opened for reading.
+func tempIn(t *testing.T, text string) *os.File {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "in.rpn")
+	if err := ioutil.WriteFile(name, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// tempOut returns a file to write to, and a function reading it back.
+func tempOut(t *testing.T) (*os.File, func() string) {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "out")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, func() string {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+}
+
+func TestScanLongLine(t *testing.T) {
+	in := tempIn(t, "0"+strings.Repeat(" 1 +", 5e5)+"\n")
+	out, read := tempOut(t)
+	if !scan(rpn.New(), in, out) {
+		t.Fatal("scan failed")
+	}
+	if got := read(); got != "[ 500000,\b ]\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoadRC(t *testing.T) {
+	rc := tempIn(t, "macro double 2 *\n")
+	defer func(name string) { rcFile = name }(rcFile)
+	rcFile = rc.Name()
+	calc := rpn.New()
+	loadRC(calc)
+	if err := calc.Eval("4 double"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calc.Format(calc.Stack()[0]); got != "8" {
+		t.Errorf("got %s, want 8", got)
+	}
+}
+
+func TestOneShot(t *testing.T) {
+	tests := []struct {
+		exprs []string
+		all   bool
+		want  string
+		ok    bool
+	}{
+		{[]string{"2 3 +"}, false, "5\n", true},
+		{[]string{"1 2", "3"}, false, "3\n", true},
+		{[]string{"1 2", "3"}, true, "1\n2\n3\n", true},
+		{[]string{"hex 255"}, false, "0xff\n", true},
+		{[]string{""}, false, "", true},
+		{[]string{"1 +", "2"}, false, "", false},
+		{[]string{"1 exit", "2"}, false, "1\n", true},
+	}
+	defer func(e exprList, a bool) { exprs, all = e, a }(exprs, all)
+	for _, test := range tests {
+		exprs, all = test.exprs, test.all
+		out, read := tempOut(t)
+		ok := oneShot(rpn.New(), out)
+		if got := read(); ok != test.ok || got != test.want {
+			t.Errorf("%q: got %q and %v, want %q and %v", test.exprs, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestScanFails(t *testing.T) {
+	in := tempIn(t, "1\n+\n2\n")
+	out, read := tempOut(t)
+	if scan(rpn.New(), in, out) {
+		t.Error("scan didn't fail with a failed line")
+	}
+	if got := read(); got != "[ 1,\b ]\n[ 1,\b ]\n[ 1,2,\b ]\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestScanJSON(t *testing.T) {
+	defer func() { output = "text" }()
+	for _, test := range []struct{ output, in string }{
+		{"json", "1 2\n+ +\n3\n"},
+		{"json", ""},
+		{"ndjson", "1 2\n+ +\n3\n"},
+	} {
+		output = test.output
+		out, read := tempOut(t)
+		scan(rpn.New(), tempIn(t, test.in), out)
+		got := read()
+		var docs []map[string]interface{}
+		if test.output == "json" {
+			if err := json.Unmarshal([]byte(got), &docs); err != nil {
+				t.Errorf("%s: %v in %s", test.output, err, got)
+			}
+		} else {
+			for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+				var doc map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &doc); err != nil {
+					t.Errorf("%s: %v in %s", test.output, err, line)
+				}
+				docs = append(docs, doc)
+			}
+		}
+		if want := strings.Count(test.in, "\n"); len(docs) != want {
+			t.Errorf("%s: got %d documents, want %d", test.output, len(docs), want)
+		}
+	}
+}
+
+func TestGetFiles(t *testing.T) {
+	defer func(i, o string, a bool) { inFile, outFile, appendOut = i, o, a }(inFile, outFile, appendOut)
+	inFile, outFile = "stdin", filepath.Join(t.TempDir(), "out")
+	for _, test := range []struct {
+		append      bool
+		write, want string
+	}{
+		{false, "one\n", "one\n"},
+		{false, "two\n", "two\n"},
+		{true, "three\n", "two\nthree\n"},
+	} {
+		appendOut = test.append
+		in, out, err := getFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in != os.Stdin {
+			t.Error("-in stdin isn't os.Stdin")
+		}
+		if _, err := out.WriteString(test.write); err != nil {
+			t.Fatal(err)
+		}
+		out.Close()
+		if got, _ := ioutil.ReadFile(outFile); string(got) != test.want {
+			t.Errorf("append %v: got %q, want %q", test.append, got, test.want)
+		}
+	}
+}
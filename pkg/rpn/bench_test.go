@@ -0,0 +1,38 @@
+package rpn
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// script returns a line of n words adding up to n/2.
+func script(n int) string {
+	return "0" + strings.Repeat(" 1 +", n/2)
+}
+
+func BenchmarkEval(b *testing.B) {
+	for _, n := range []int{1e2, 1e3, 1e4, 1e6} {
+		line := script(n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := newTest().Eval(line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMacroCalls(b *testing.B) {
+	in := newTest()
+	if err := in.Eval("macro inc 1 +"); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := in.Eval("0 10000 [ inc ] times drop"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package rpn
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Arbitrary precision versions of the math functions. They compute with
+// guardBits extra bits and return results accurate to at least prec bits,
+// rounding them down to the working precision is left to the caller.
+
+const guardBits = 64
+
+func newFloat(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+// negligible tells if adding term to sum no longer changes its first wp bits.
+func negligible(term, sum *big.Float, wp uint) bool {
+	return term.Sign() == 0 || sum.Sign() != 0 && term.MantExp(nil) < sum.MantExp(nil)-int(wp)
+}
+
+// consts caches pi and ln 2 at the highest precision computed so far.
+var consts struct {
+	sync.Mutex
+	pi, ln2 *big.Float
+}
+
+func cachedConst(c **big.Float, prec uint, compute func(uint) *big.Float) *big.Float {
+	consts.Lock()
+	defer consts.Unlock()
+	if *c == nil || (*c).Prec() < prec {
+		*c = compute(prec)
+	}
+	return newFloat(prec).Set(*c)
+}
+
+// bigPi returns pi to prec bits.
+func bigPi(prec uint) *big.Float {
+	return cachedConst(&consts.pi, prec, computePi)
+}
+
+// bigLn2 returns ln 2 to prec bits.
+func bigLn2(prec uint) *big.Float {
+	return cachedConst(&consts.ln2, prec, func(prec uint) *big.Float {
+		// ln 2 = 2 atanh(1/3)
+		wp := prec + guardBits
+		third := newFloat(wp).Quo(newFloat(wp).SetInt64(1), newFloat(wp).SetInt64(3))
+		r := atanhSeries(third, wp)
+		return r.SetMantExp(r, 1)
+	})
+}
+
+// bigE returns e to prec bits.
+func bigE(prec uint) *big.Float {
+	r, _ := bigExp(newFloat(prec).SetInt64(1), prec)
+	return r
+}
+
+// computePi uses the Gauss-Legendre algorithm, which doubles the number of
+// correct digits on each iteration.
+func computePi(prec uint) *big.Float {
+	wp := prec + guardBits
+	a := newFloat(wp).SetInt64(1)
+	b := newFloat(wp).Sqrt(newFloat(wp).SetFloat64(0.5))
+	t := newFloat(wp).SetFloat64(0.25)
+	p := newFloat(wp).SetInt64(1)
+	for {
+		an := newFloat(wp).Add(a, b)
+		an.SetMantExp(an, -1)
+		b.Sqrt(newFloat(wp).Mul(a, b))
+		d := newFloat(wp).Sub(a, an)
+		d.Mul(d, d)
+		t.Sub(t, d.Mul(d, p))
+		a = an
+		p.SetMantExp(p, 1)
+		if diff := newFloat(wp).Sub(a, b); diff.Sign() == 0 || diff.MantExp(nil) < -int(wp/2) {
+			break
+		}
+	}
+	s := newFloat(wp).Add(a, b)
+	s.Mul(s, s)
+	return s.Quo(s, t.SetMantExp(t, 2))
+}
+
+// atanhSeries sums z + z³/3 + z⁵/5 + ..., it converges fast for small |z|.
+func atanhSeries(z *big.Float, wp uint) *big.Float {
+	return arctanSeries(z, wp, false)
+}
+
+// arctanSeries sums z - z³/3 + z⁵/5 - ..., or atanh's series when
+// alternate is false.
+func arctanSeries(z *big.Float, wp uint, alternate bool) *big.Float {
+	z2 := newFloat(wp).Mul(z, z)
+	if alternate {
+		z2.Neg(z2)
+	}
+	sum := newFloat(wp).Set(z)
+	pow := newFloat(wp).Set(z)
+	term := newFloat(wp)
+	for n := int64(3); ; n += 2 {
+		pow.Mul(pow, z2)
+		term.Quo(pow, newFloat(wp).SetInt64(n))
+		if negligible(term, sum, wp) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// bigLn returns the natural logarithm of x. The mantissa is brought close to
+// 1 with square roots and then fed to the atanh series:
+// ln m = 2 atanh((m-1)/(m+1)).
+func bigLn(x *big.Float, prec uint) (*big.Float, error) {
+	switch {
+	case x.Sign() < 0:
+		return nil, ErrDomain{Value: x.String()}
+	case x.Sign() == 0:
+		return newFloat(prec).SetInf(true), nil
+	case x.IsInf():
+		return newFloat(prec).SetInf(false), nil
+	}
+	wp := prec + guardBits + 16
+	e := x.MantExp(nil)
+	if e == 1 {
+		// keep numbers in [1, 2) whole, so ln(1+ε) doesn't cancel with ln 2
+		e = 0
+	}
+	m := newFloat(wp).Set(x)
+	m.SetMantExp(m, -e)
+	one := newFloat(wp).SetInt64(1)
+	limit := newFloat(wp).SetMantExp(one, -8)
+	roots := 0
+	for d := newFloat(wp).Sub(m, one); d.Abs(d).Cmp(limit) > 0; d.Sub(m, one) {
+		m.Sqrt(m)
+		roots++
+	}
+	z := newFloat(wp).Quo(newFloat(wp).Sub(m, one), newFloat(wp).Add(m, one))
+	r := atanhSeries(z, wp)
+	r.SetMantExp(r, roots+1)
+	if e != 0 {
+		l2 := bigLn2(wp + 32)
+		r.Add(r, l2.Mul(l2, newFloat(wp).SetInt64(int64(e))))
+	}
+	return r, nil
+}
+
+// bigLog10 returns the decimal logarithm of x.
+func bigLog10(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	l, err := bigLn(x, wp)
+	if err != nil || l.IsInf() {
+		return l, err
+	}
+	ln10, _ := bigLn(newFloat(wp).SetInt64(10), wp)
+	return l.Quo(l, ln10), nil
+}
+
+// maxExpArg is about the largest x whose e**x fits in a big.Float.
+var maxExpArg = big.NewFloat(1.4e9)
+
+// bigExp returns e**x. The argument is reduced to x = k ln 2 + r, r is
+// halved a few times before summing the series, and the sum is squared back.
+func bigExp(x *big.Float, prec uint) (*big.Float, error) {
+	switch {
+	case x.Sign() == 0:
+		return newFloat(prec).SetInt64(1), nil
+	case x.IsInf() && x.Sign() > 0, x.Cmp(maxExpArg) > 0:
+		return newFloat(prec).SetInf(false), nil
+	case x.IsInf(), newFloat(0).Neg(x).Cmp(maxExpArg) > 0:
+		return newFloat(prec).SetInt64(0), nil
+	}
+	const halvings = 8
+	xe := x.MantExp(nil)
+	if xe < 0 {
+		xe = 0
+	}
+	wp := prec + guardBits + halvings + uint(xe)
+	l2 := bigLn2(wp)
+	k, _ := newFloat(wp).Quo(x, l2).Int64()
+	r := newFloat(wp).Mul(l2, newFloat(wp).SetInt64(k))
+	r.Sub(x, r)
+	r.SetMantExp(r, -halvings)
+	sum := expSeries(r, wp)
+	for i := 0; i < halvings; i++ {
+		sum.Mul(sum, sum)
+	}
+	return sum.SetMantExp(sum, int(k)), nil
+}
+
+// expSeries sums 1 + r + r²/2! + r³/3! + ...
+func expSeries(r *big.Float, wp uint) *big.Float {
+	sum := newFloat(wp).SetInt64(1)
+	term := newFloat(wp).SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, newFloat(wp).SetInt64(n))
+		if negligible(term, sum, wp) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// bigSqrt returns the square root of x.
+func bigSqrt(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() < 0 {
+		return nil, ErrDomain{Value: x.String()}
+	}
+	if x.Sign() == 0 {
+		return newFloat(prec), nil
+	}
+	return newFloat(prec + guardBits).Sqrt(x), nil
+}
+
+// bigPow returns a**b, by repeated squaring when b is an integer and as
+// e**(b ln a) otherwise.
+func bigPow(a, b *big.Float, prec uint) (*big.Float, error) {
+	if b.IsInf() {
+		return nil, ErrDomain{Value: b.String()}
+	}
+	if b.IsInt() && b.MantExp(nil) <= 64 {
+		n, _ := b.Int(nil)
+		return powInt(a, n, prec)
+	}
+	switch {
+	case a.Sign() < 0:
+		return nil, ErrDomain{Value: a.String()}
+	case a.Sign() == 0 && b.Sign() < 0:
+		return nil, ErrDivisionByZero{}
+	case a.Sign() == 0:
+		return newFloat(prec), nil
+	}
+	be := b.MantExp(nil)
+	if be < 0 {
+		be = 0
+	}
+	wp := prec + guardBits + uint(be) + 32
+	l, _ := bigLn(a, wp)
+	if l.IsInf() {
+		if l.Sign() == b.Sign() {
+			return newFloat(prec).SetInf(false), nil
+		}
+		return newFloat(prec), nil
+	}
+	return bigExp(l.Mul(l, b), prec)
+}
+
+// powInt returns a**n by repeated squaring.
+func powInt(a *big.Float, n *big.Int, prec uint) (*big.Float, error) {
+	if n.Sign() < 0 && a.Sign() == 0 {
+		return nil, ErrDivisionByZero{}
+	}
+	wp := prec + guardBits + uint(n.BitLen())
+	r := newFloat(wp).SetInt64(1)
+	sq := newFloat(wp).Set(a)
+	e := new(big.Int).Abs(n)
+	for i := 0; i < e.BitLen(); i++ {
+		if e.Bit(i) == 1 {
+			r.Mul(r, sq)
+		}
+		if i+1 < e.BitLen() {
+			sq.Mul(sq, sq)
+		}
+	}
+	if n.Sign() < 0 {
+		r.Quo(newFloat(wp).SetInt64(1), r)
+	}
+	return r, nil
+}
+
+// roundInt rounds x to the nearest integer, halves away from zero.
+func roundInt(x *big.Float) *big.Int {
+	h := big.NewFloat(0.5)
+	if x.Sign() < 0 {
+		h.Neg(h)
+	}
+	i, _ := newFloat(x.Prec()+1).Add(x, h).Int(nil)
+	return i
+}
+
+// maxTrigExp bounds the exponent of the trigonometric functions' arguments,
+// reducing larger ones would need a pi with too many bits.
+const maxTrigExp = 1 << 16
+
+// reduceHalfPi returns r and q such that x = k·π/2 + r, with |r| ≤ π/4 and
+// q = k mod 4.
+func reduceHalfPi(x *big.Float, wp uint) (*big.Float, int, error) {
+	xe := x.MantExp(nil)
+	if x.IsInf() || xe > maxTrigExp {
+		return nil, 0, ErrDomain{Value: x.String()}
+	}
+	if xe < 0 {
+		xe = 0
+	}
+	pwp := wp + uint(xe)
+	halfPi := bigPi(pwp)
+	halfPi.SetMantExp(halfPi, -1)
+	k := roundInt(newFloat(pwp).Quo(x, halfPi))
+	r := newFloat(pwp).Mul(newFloat(pwp).SetInt(k), halfPi)
+	r.Sub(x, r)
+	q := new(big.Int).Mod(k, big.NewInt(4)).Int64()
+	return newFloat(wp).Set(r), int(q), nil
+}
+
+// sinSeries sums r - r³/3! + r⁵/5! - ...
+func sinSeries(r *big.Float, wp uint) *big.Float {
+	return trigSeries(newFloat(wp).Set(r), r, 2, wp)
+}
+
+// cosSeries sums 1 - r²/2! + r⁴/4! - ...
+func cosSeries(r *big.Float, wp uint) *big.Float {
+	return trigSeries(newFloat(wp).SetInt64(1), r, 1, wp)
+}
+
+// trigSeries sums the series starting at first, each term is the previous
+// one times -r²/(n(n+1)).
+func trigSeries(first, r *big.Float, n int64, wp uint) *big.Float {
+	r2 := newFloat(wp).Mul(r, r)
+	r2.Neg(r2)
+	sum := newFloat(wp).Set(first)
+	term := newFloat(wp).Set(first)
+	for ; ; n += 2 {
+		term.Mul(term, r2)
+		term.Quo(term, newFloat(wp).SetInt64(n*(n+1)))
+		if negligible(term, sum, wp) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// bigSin returns the sine of x.
+func bigSin(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	r, q, err := reduceHalfPi(x, wp)
+	if err != nil {
+		return nil, err
+	}
+	switch q {
+	case 1:
+		return cosSeries(r, wp), nil
+	case 2:
+		r = sinSeries(r, wp)
+		return r.Neg(r), nil
+	case 3:
+		r = cosSeries(r, wp)
+		return r.Neg(r), nil
+	}
+	return sinSeries(r, wp), nil
+}
+
+// bigCos returns the cosine of x.
+func bigCos(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	r, q, err := reduceHalfPi(x, wp)
+	if err != nil {
+		return nil, err
+	}
+	switch q {
+	case 1:
+		r = sinSeries(r, wp)
+		return r.Neg(r), nil
+	case 2:
+		r = cosSeries(r, wp)
+		return r.Neg(r), nil
+	case 3:
+		return sinSeries(r, wp), nil
+	}
+	return cosSeries(r, wp), nil
+}
+
+// bigTan returns the tangent of x.
+func bigTan(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	s, err := bigSin(x, wp)
+	if err != nil {
+		return nil, err
+	}
+	c, _ := bigCos(x, wp)
+	return s.Quo(s, c), nil
+}
+
+// bigAtan returns the arc tangent of x. Arguments above 1 are inverted, and
+// the rest halved with atan x = 2 atan(x / (1 + √(1+x²))) before summing the
+// series.
+func bigAtan(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	if x.Sign() == 0 {
+		return newFloat(prec), nil
+	}
+	halfPi := bigPi(wp)
+	halfPi.SetMantExp(halfPi, -1)
+	if x.IsInf() {
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+	const halvings = 4
+	one := newFloat(wp).SetInt64(1)
+	a := newFloat(wp).Abs(x)
+	invert := a.Cmp(one) > 0
+	if invert {
+		a.Quo(one, a)
+	}
+	for i := 0; i < halvings; i++ {
+		d := newFloat(wp).Mul(a, a)
+		d.Sqrt(d.Add(d, one))
+		a.Quo(a, d.Add(d, one))
+	}
+	r := arctanSeries(a, wp, true)
+	r.SetMantExp(r, halvings)
+	if invert {
+		r.Sub(halfPi, r)
+	}
+	if x.Sign() < 0 {
+		r.Neg(r)
+	}
+	return r, nil
+}
+
+// bigAsin returns the arc sine of x, as atan(x / √(1-x²)).
+func bigAsin(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	one := newFloat(wp).SetInt64(1)
+	switch newFloat(wp).Abs(x).Cmp(one) {
+	case 1:
+		return nil, ErrDomain{Value: x.String()}
+	case 0:
+		halfPi := bigPi(wp)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+	// 1-x² as (1-x)(1+x), it cancels less near ±1
+	d := newFloat(wp).Sub(one, x)
+	d.Mul(d, newFloat(wp).Add(one, x))
+	d.Sqrt(d)
+	return bigAtan(d.Quo(x, d), wp)
+}
+
+// bigAcos returns the arc cosine of x, as 2 atan(√((1-x)/(1+x))).
+func bigAcos(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	one := newFloat(wp).SetInt64(1)
+	switch {
+	case newFloat(wp).Abs(x).Cmp(one) > 0:
+		return nil, ErrDomain{Value: x.String()}
+	case x.Cmp(newFloat(wp).Neg(one)) == 0:
+		return bigPi(wp), nil
+	}
+	d := newFloat(wp).Sub(one, x)
+	d.Quo(d, newFloat(wp).Add(one, x))
+	r, err := bigAtan(d.Sqrt(d), wp)
+	if err != nil {
+		return nil, err
+	}
+	return r.SetMantExp(r, 1), nil
+}
+
+// bigSinh returns the hyperbolic sine of x, from its series when |x| < 1 so
+// e**x - e**-x doesn't cancel.
+func bigSinh(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	if newFloat(wp).Abs(x).Cmp(newFloat(wp).SetInt64(1)) < 0 {
+		x2 := newFloat(wp).Mul(x, x)
+		sum := newFloat(wp).Set(x)
+		term := newFloat(wp).Set(x)
+		for n := int64(2); ; n += 2 {
+			term.Mul(term, x2)
+			term.Quo(term, newFloat(wp).SetInt64(n*(n+1)))
+			if negligible(term, sum, wp) {
+				return sum, nil
+			}
+			sum.Add(sum, term)
+		}
+	}
+	ep, _ := bigExp(x, wp)
+	en, _ := bigExp(newFloat(wp).Neg(x), wp)
+	ep.Sub(ep, en)
+	return ep.SetMantExp(ep, -1), nil
+}
+
+// bigCosh returns the hyperbolic cosine of x.
+func bigCosh(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	ep, _ := bigExp(x, wp)
+	en, _ := bigExp(newFloat(wp).Neg(x), wp)
+	ep.Add(ep, en)
+	return ep.SetMantExp(ep, -1), nil
+}
+
+// bigTanh returns the hyperbolic tangent of x, ±1 once e**-2|x| is below
+// the precision.
+func bigTanh(x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	if newFloat(wp).Abs(x).Cmp(newFloat(wp).SetInt64(int64(wp))) > 0 {
+		return newFloat(prec).SetInt64(int64(x.Sign())), nil
+	}
+	s, _ := bigSinh(x, wp)
+	c, _ := bigCosh(x, wp)
+	return s.Quo(s, c), nil
+}
+
+// bigAtan2 returns the angle of the point (x, y), in (-π, π].
+func bigAtan2(y, x *big.Float, prec uint) (*big.Float, error) {
+	wp := prec + guardBits
+	switch {
+	case x.Sign() == 0 && y.Sign() == 0:
+		return newFloat(prec), nil
+	case x.Sign() == 0:
+		halfPi := bigPi(wp)
+		halfPi.SetMantExp(halfPi, -1)
+		if y.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi, nil
+	}
+	r, err := bigAtan(newFloat(wp).Quo(y, x), wp)
+	if err != nil || x.Sign() > 0 {
+		return r, err
+	}
+	if y.Sign() < 0 {
+		return r.Sub(r, bigPi(wp)), nil
+	}
+	return r.Add(r, bigPi(wp)), nil
+}
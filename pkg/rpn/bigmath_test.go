@@ -0,0 +1,101 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+// The digits below were computed independently to 120 significant digits,
+// about 400 bits.
+var bigmathTests = []struct {
+	name string
+	f    func(prec uint) (*big.Float, error)
+	want string
+}{
+	{"pi", func(prec uint) (*big.Float, error) { return bigPi(prec), nil },
+		"3.14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798214808651328230664"},
+	{"e", func(prec uint) (*big.Float, error) { return bigE(prec), nil },
+		"2.71828182845904523536028747135266249775724709369995957496696762772407663035354759457138217852516642742746639193200305992"},
+	{"ln2", func(prec uint) (*big.Float, error) { return bigLn2(prec), nil },
+		"0.693147180559945309417232121458176568075500134360255254120680009493393621969694715605863326996418687542001481020570685733"},
+	{"ln 2", func(prec uint) (*big.Float, error) { return bigLn(big.NewFloat(2), prec) },
+		"0.693147180559945309417232121458176568075500134360255254120680009493393621969694715605863326996418687542001481020570685733"},
+	{"exp -2.5", func(prec uint) (*big.Float, error) { return bigExp(big.NewFloat(-2.5), prec) },
+		"0.082084998623898795169528674467159807837804121015436648845758410515224756880410971309751571521236465908717714823832595793"},
+	{"exp 10", func(prec uint) (*big.Float, error) { return bigExp(big.NewFloat(10), prec) },
+		"22026.4657948067165169579006452842443663535126185567810742354263552252028185707925751991209681645258954515555010924578366"},
+	{"log 2", func(prec uint) (*big.Float, error) { return bigLog10(big.NewFloat(2), prec) },
+		"0.301029995663981195213738894724493026768189881462108541310427461127108189274424509486927252118186172040684477191430995379"},
+	{"log 0.003", func(prec uint) (*big.Float, error) { return bigLog10(exactFloat("0.003"), prec) },
+		"-2.52287874528033756270497209674488469079987113580930413517013435969477084721633887695703164435238369848953530723174795410"},
+	{"sin 1", func(prec uint) (*big.Float, error) { return bigSin(big.NewFloat(1), prec) },
+		"0.841470984807896506652502321630298999622563060798371065672751709991910404391239668948639743543052695854349037907920674293"},
+	{"sin 100", func(prec uint) (*big.Float, error) { return bigSin(big.NewFloat(100), prec) },
+		"-0.50636564110975879365655761045978543206503272129065732344339247359435791341947669649923666451292739220724408939256384042"},
+	{"cos 1", func(prec uint) (*big.Float, error) { return bigCos(big.NewFloat(1), prec) },
+		"0.540302305868139717400936607442976603732310420617922227670097255381100394774471764517951856087183089343571731160030089097"},
+	{"cos 0.5", func(prec uint) (*big.Float, error) { return bigCos(big.NewFloat(0.5), prec) },
+		"0.877582561890372716116281582603829651991645197109744052997610868315950763274213947405794184084682258355478400593109053993"},
+	{"sqrt 2", func(prec uint) (*big.Float, error) { return bigSqrt(big.NewFloat(2), prec) },
+		"1.41421356237309504880168872420969807856967187537694807317667973799073247846210703885038753432764157273501384623091229702"},
+	{"sqrt 1000.5", func(prec uint) (*big.Float, error) { return bigSqrt(big.NewFloat(1000.5), prec) },
+		"31.6306813078694212308678948608540661849800400880819635064954489577401548938785535976572530206637134292121820735332661968"},
+	{"pow 3 2.5", func(prec uint) (*big.Float, error) { return bigPow(big.NewFloat(3), big.NewFloat(2.5), prec) },
+		"15.5884572681198956417470170735528513024852472842934256525022628150673971521792003337303156808152371810806352727386603273"},
+	{"pow 0.5 0.3", func(prec uint) (*big.Float, error) { return bigPow(big.NewFloat(0.5), exactFloat("0.3"), prec) },
+		"0.812252396356235522609709382775281651285204974432159442532493713054220743435014885624736129816028827453355335775923236157"},
+	{"tan 1", func(prec uint) (*big.Float, error) { return bigTan(big.NewFloat(1), prec) },
+		"1.55740772465490223050697480745836017308725077238152003838394660569886139715172728955509996520224298380463382141174816661"},
+	{"tan 1.5", func(prec uint) (*big.Float, error) { return bigTan(big.NewFloat(1.5), prec) },
+		"14.1014199471717193876460836519877564456595435772358618661232675860896962704141552686487029263094422870458678385945659197"},
+	{"asin 0.5", func(prec uint) (*big.Float, error) { return bigAsin(big.NewFloat(0.5), prec) },
+		"0.523598775598298873077107230546583814032861566562517636829157432051302734381034833104672470890352844663691347752213717775"},
+	{"asin 0.99", func(prec uint) (*big.Float, error) { return bigAsin(exactFloat("0.99"), prec) },
+		"1.42925685347046940048553233466472442710460176914779971717932129310252724978631861144318723931871601320485767386441816363"},
+	{"acos 0.5", func(prec uint) (*big.Float, error) { return bigAcos(big.NewFloat(0.5), prec) },
+		"1.04719755119659774615421446109316762806572313312503527365831486410260546876206966620934494178070568932738269550442743555"},
+	{"acos -0.25", func(prec uint) (*big.Float, error) { return bigAcos(big.NewFloat(-0.25), prec) },
+		"1.82347658193697527271697912863346241435077843278439110412139607489448326362412572172576615489907313559616664616605521989"},
+	{"atan 1", func(prec uint) (*big.Float, error) { return bigAtan(big.NewFloat(1), prec) },
+		"0.785398163397448309615660845819875721049292349843776455243736148076954101571552249657008706335529266995537021628320576662"},
+	{"atan 10", func(prec uint) (*big.Float, error) { return bigAtan(big.NewFloat(10), prec) },
+		"1.47112767430373459185287557176173085185530637718323826247196351934388045569555384489340478823677216241151565684781375435"},
+	{"sinh 1", func(prec uint) (*big.Float, error) { return bigSinh(big.NewFloat(1), prec) },
+		"1.17520119364380145688238185059560081515571798133409587022956541301330756730432389560711745208962339184041953332757953236"},
+	{"sinh 0.001", func(prec uint) (*big.Float, error) { return bigSinh(exactFloat("0.001"), prec) },
+		"0.00100000016666667500000019841270116843036014911030970058824308163213647283096796781745139070059980620151502419950072451483"},
+	{"cosh 2", func(prec uint) (*big.Float, error) { return bigCosh(big.NewFloat(2), prec) },
+		"3.76219569108363145956221347777374610829397355823071160277764334758832358509027272666070530378488942176441522422755620917"},
+	{"tanh 0.5", func(prec uint) (*big.Float, error) { return bigTanh(big.NewFloat(0.5), prec) },
+		"0.462117157260009758502318483643672548730289280330113038552731815838080906140409278774949064151962490584348932986281549133"},
+}
+
+// exactFloat parses s with 512 bits, which the arguments of the tests need
+// to be close enough to their decimal values.
+func exactFloat(s string) *big.Float {
+	f, _, err := big.ParseFloat(s, 10, 512, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestBigmath(t *testing.T) {
+	for _, test := range bigmathTests {
+		for _, prec := range []uint{24, 53, 64, 113, 256, 350} {
+			got, err := test.f(prec)
+			if err != nil {
+				t.Errorf("%s at %d bits: %v", test.name, prec, err)
+				continue
+			}
+			got = newFloat(prec).Set(got)
+			want := exactFloat(test.want)
+			// within an ulp of the digits, at the precision
+			diff := new(big.Float).Sub(got, want)
+			ulp := new(big.Float).SetMantExp(big.NewFloat(1), want.MantExp(nil)-int(prec))
+			if diff.Abs(diff).Cmp(ulp) > 0 {
+				t.Errorf("%s at %d bits: got %s, want %s", test.name, prec, got.Text('g', int(prec)/3), test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rpn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// hostOrder is the byte order of the machine, which htons and friends swap
+// from.
+var hostOrder binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// fixed returns the n bytes of the integer v, big-endian, negative integers
+// in two's complement.
+func fixed(v Var, n int, signed bool) ([]byte, error) {
+	i, err := toInt(v)
+	if err != nil {
+		return nil, err
+	}
+	return fixedInt(i, n, signed)
+}
+
+func fixedInt(i *big.Int, n int, signed bool) ([]byte, error) {
+	i = new(big.Int).Set(i)
+	lim := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	lo, hi := new(big.Int), lim
+	if signed {
+		hi = new(big.Int).Rsh(lim, 1)
+		lo = new(big.Int).Neg(hi)
+	} else if i.Sign() < 0 {
+		// allowed for bit patterns, as with a signed word size
+		lo = new(big.Int).Neg(new(big.Int).Rsh(lim, 1))
+	}
+	if i.Cmp(lo) < 0 || i.Cmp(hi) >= 0 {
+		return nil, ErrDomain{Value: i.String()}
+	}
+	if i.Sign() < 0 {
+		i.Add(i, lim)
+	}
+	b := i.Bytes()
+	return append(make([]byte, n-len(b)), b...), nil
+}
+
+// unfixed returns the integer of big-endian bytes.
+func unfixed(b []byte, signed bool) *big.Int {
+	i := new(big.Int).SetBytes(b)
+	if signed && len(b) > 0 && b[0]&0x80 != 0 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return i
+}
+
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// bswap returns an op swapping the order of the n bytes of an integer.
+func bswap(n int) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		b, err := fixed(args[0], n, false)
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(unfixed(reverse(b), false)), nil
+	}}
+}
+
+// hton returns an op converting an integer of n bytes between the host and
+// network byte orders, which swaps them on little-endian machines.
+func hton(n int) op {
+	if hostOrder == binary.BigEndian {
+		return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+			if _, err := fixed(args[0], n, false); err != nil {
+				return nil, err
+			}
+			return args[:1], nil
+		}}
+	}
+	return bswap(n)
+}
+
+// packCode is a code of a pack format, like the 2 H of >2H.
+type packCode struct {
+	count int
+	code  byte
+}
+
+// packSizes are the bytes of the codes of pack formats, as in Python's
+// struct module.
+var packSizes = map[byte]int{
+	'x': 1, 'b': 1, 'B': 1, '?': 1, 'h': 2, 'H': 2, 'i': 4, 'I': 4, 'l': 4, 'L': 4,
+	'q': 8, 'Q': 8, 'e': 2, 'f': 4, 'd': 8, 's': 1,
+}
+
+var packFloats = map[byte]ieee{'e': f16, 'f': f32, 'd': f64}
+
+// parsePack parses a format like >HHI into its byte order and codes.
+func parsePack(format string) (binary.ByteOrder, []packCode, error) {
+	bad := ErrDomain{Value: strconv.Quote(format)}
+	order := hostOrder
+	if format != "" {
+		switch format[0] {
+		case '<':
+			order, format = binary.LittleEndian, format[1:]
+		case '>', '!':
+			order, format = binary.BigEndian, format[1:]
+		case '=', '@':
+			format = format[1:]
+		}
+	}
+	var codes []packCode
+	for i := 0; i < len(format); i++ {
+		if format[i] == ' ' {
+			continue
+		}
+		j := i
+		for j < len(format) && format[j] >= '0' && format[j] <= '9' {
+			j++
+		}
+		count := 1
+		if j > i {
+			n, err := strconv.Atoi(format[i:j])
+			if err != nil || n > 1<<16 {
+				return nil, nil, bad
+			}
+			count = n
+		}
+		if j == len(format) || packSizes[format[j]] == 0 {
+			return nil, nil, bad
+		}
+		codes = append(codes, packCode{count, format[j]})
+		i = j
+	}
+	return order, codes, nil
+}
+
+// values returns how many items the codes pack, a string being one.
+func values(codes []packCode) int {
+	n := 0
+	for _, c := range codes {
+		switch c.code {
+		case 'x':
+		case 's':
+			n++
+		default:
+			n += c.count
+		}
+	}
+	return n
+}
+
+// pack encodes args with the codes.
+func (in *Interpreter) pack(order binary.ByteOrder, codes []packCode, args []Var) ([]byte, error) {
+	var out []byte
+	for _, c := range codes {
+		switch c.code {
+		case 'x':
+			out = append(out, make([]byte, c.count)...)
+			continue
+		case 's':
+			s, err := toString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			b := append([]byte(s), make([]byte, c.count)...)
+			out, args = append(out, b[:c.count]...), args[1:]
+			continue
+		}
+		for k := 0; k < c.count; k++ {
+			b, err := in.packValue(c.code, args[0])
+			if err != nil {
+				return nil, err
+			}
+			if order == binary.LittleEndian {
+				reverse(b)
+			}
+			out, args = append(out, b...), args[1:]
+		}
+	}
+	return out, nil
+}
+
+// packValue returns the big-endian bytes of a value for a code.
+func (in *Interpreter) packValue(code byte, v Var) ([]byte, error) {
+	switch code {
+	case 'e', 'f', 'd':
+		bits, err := packFloats[code].encodeVar(in, v)
+		if err != nil {
+			return nil, err
+		}
+		return fixedInt(bits, packSizes[code], false)
+	case '?':
+		c, err := sign(v)
+		if err != nil {
+			return nil, err
+		}
+		if c != 0 {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	}
+	signed := code >= 'a' && code <= 'z'
+	return fixed(v, packSizes[code], signed)
+}
+
+// unpack decodes b with the codes.
+func (in *Interpreter) unpack(order binary.ByteOrder, codes []packCode, b []byte) ([]Var, error) {
+	size := 0
+	for _, c := range codes {
+		size += c.count * packSizes[c.code]
+	}
+	if size != len(b) {
+		return nil, ErrDomain{Value: fmt.Sprintf("%d bytes, not %d,", len(b), size)}
+	}
+	var res []Var
+	for _, c := range codes {
+		switch c.code {
+		case 'x':
+			b = b[c.count:]
+			continue
+		case 's':
+			res, b = append(res, Var{Type: String, B: append([]byte(nil), b[:c.count]...)}), b[c.count:]
+			continue
+		}
+		n := packSizes[c.code]
+		for k := 0; k < c.count; k++ {
+			v := append([]byte(nil), b[:n]...)
+			b = b[n:]
+			if order == binary.LittleEndian {
+				reverse(v)
+			}
+			switch c.code {
+			case 'e', 'f', 'd':
+				f := packFloats[c.code]
+				z, err := f.decode(unfixed(v, false))
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, number(f.exact(in, z))...)
+			case '?':
+				res = append(res, in.boolean(v[0] != 0)...)
+			default:
+				res = append(res, in.integer(unfixed(v, c.code >= 'a' && c.code <= 'z'))...)
+			}
+		}
+	}
+	return res, nil
+}
+
+// formatBytes formats the bytes of strings that aren't text, like <00 50>.
+func formatBytes(b []byte) string {
+	return "<" + strings.TrimSpace(fmt.Sprintf("% x", b)) + ">"
+}
@@ -0,0 +1,74 @@
+package rpn
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestByteOrder(t *testing.T) {
+	testEval(t, []evalTest{
+		{"0x1234 bswap16 hex", "0x3412"},
+		{"0x12345678 bswap32 hex", "0x78563412"},
+		{"0x0102030405060708 bswap64 hex", "0x807060504030201"},
+		{"-1 bswap16", "65535"},
+		{"0x1234 hns", "<12 34>"},
+		{"0x12345678 hnl", "<12 34 56 78>"},
+		{"0x1234 hns nhs hex", "0x1234"},
+		{"0x12345678 hnl nhl hex", "0x12345678"},
+	})
+	// the host order words swap on little-endian machines only
+	swapped := map[bool][]evalTest{
+		true: {
+			{"0x1234 htons hex", "0x3412"},
+			{"0x1234 ntohs hex", "0x3412"},
+			{"0x12345678 htonl hex", "0x78563412"},
+			{"0x12345678 ntohl hex", "0x78563412"},
+			{"1 htonll hex", "0x100000000000000"},
+			{"1 ntohll hex", "0x100000000000000"},
+		},
+		false: {
+			{"0x1234 htons hex", "0x1234"},
+			{"0x1234 ntohs hex", "0x1234"},
+			{"0x12345678 htonl hex", "0x12345678"},
+			{"0x12345678 ntohl hex", "0x12345678"},
+			{"1 htonll hex", "0x1"},
+			{"1 ntohll hex", "0x1"},
+		},
+	}
+	testEval(t, swapped[hostOrder == binary.LittleEndian])
+}
+
+func TestPack(t *testing.T) {
+	testEval(t, []evalTest{
+		{`80 443 7 ">HHI" pack`, "<00 50 01 bb 00 00 00 07>"},
+		{`80 443 7 "!HHI" pack ">HHI" unpack`, "80 443 7"},
+		{`-1 2 3.5 1 "<bhf?" pack`, "<ff 02 00 00 00 60 40 01>"},
+		{`-1 2 3.5 1 "<bhf?" pack "<bhf?" unpack`, "-1 2 3.5 1"},
+		{`1 2 "<2H" pack`, "<01 00 02 00>"},
+		{`-2 ">q" pack`, "<ff ff ff ff ff ff ff fe>"},
+		{`-2 ">q" pack ">Q" unpack hex`, "0xfffffffffffffffe"},
+		{`1.5 ">e" pack`, "<3e 00>"},
+		{`0.1 ">d" pack ">d" unpack`, "0.10000000000000000555"},
+		{`"ab" 3 ">4sxB" pack`, "<61 62 00 00 00 03>"},
+		{`"ab" 3 ">4sxB" pack ">4sxB" unpack`, "<61 62 00 00> 3"},
+		{`">" pack`, `""`},
+	})
+}
+
+func TestPackErrors(t *testing.T) {
+	for _, line := range []string{
+		`256 ">B" pack`,
+		`-129 ">b" pack`,
+		`1 ">Z" pack`,
+		`">H" pack`,
+		`"00" hex> ">H" unpack`,
+		`"000000" hex> ">H" unpack`,
+		"0x10000 bswap16",
+		"0x100000000 hnl",
+		`"00" hex> nhs`,
+	} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package rpn
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+	"math/big"
+	"strconv"
+)
+
+// checksum returns an op pushing the integer checksum of the bytes of a
+// string.
+func checksum(sum func(b []byte) uint64) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(new(big.Int).SetUint64(sum([]byte(s)))), nil
+	}}
+}
+
+// digest returns an op pushing the digest of the bytes of a string, as a
+// string of bytes.
+func digest(sum func(b []byte) []byte) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: String, B: sum([]byte(s))}}, nil
+	}}
+}
+
+// encoder returns an op encoding the bytes of a string as text.
+func encoder(encode func(b []byte) string) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return str(encode([]byte(s))), nil
+	}}
+}
+
+// decoder returns an op decoding the text of a string to its bytes.
+func decoder(decode func(s string) ([]byte, error)) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := decode(s)
+		if err != nil {
+			return nil, ErrDomain{Value: strconv.Quote(s)}
+		}
+		return []Var{{Type: String, B: b}}, nil
+	}}
+}
+
+func crc32Sum(b []byte) uint64 { return uint64(crc32.ChecksumIEEE(b)) }
+
+// crc16 is CRC-16/ARC, the reflected 0x8005 polynomial from 0.
+func crc16(b []byte) uint64 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return uint64(crc)
+}
+
+// fletcher16 is Fletcher's checksum of the bytes, modulo 255.
+func fletcher16(b []byte) uint64 {
+	var lo, hi uint32
+	for _, c := range b {
+		lo = (lo + uint32(c)) % 255
+		hi = (hi + lo) % 255
+	}
+	return uint64(hi<<8 | lo)
+}
+
+// inetsum is the Internet checksum of RFC 1071, the one's complement of the
+// one's complement sum of the big-endian 16 bit words, an odd byte padded
+// with a zero.
+func inetsum(b []byte) uint64 {
+	var sum uint32
+	for i := 0; i < len(b); i += 2 {
+		w := uint32(b[i]) << 8
+		if i+1 < len(b) {
+			w |= uint32(b[i+1])
+		}
+		sum += w
+		sum = sum&0xffff + sum>>16
+	}
+	return uint64(^uint16(sum))
+}
+
+func adler32Sum(b []byte) uint64 { return uint64(adler32.Checksum(b)) }
+
+func fnv32(b []byte) uint64 {
+	h := fnv.New32a()
+	h.Write(b)
+	return uint64(h.Sum32())
+}
+
+func fnv64(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+func md5Sum(b []byte) []byte {
+	s := md5.Sum(b)
+	return s[:]
+}
+
+func sha1Sum(b []byte) []byte {
+	s := sha1.Sum(b)
+	return s[:]
+}
+
+func sha256Sum(b []byte) []byte {
+	s := sha256.Sum256(b)
+	return s[:]
+}
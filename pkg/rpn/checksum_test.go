@@ -0,0 +1,38 @@
+package rpn
+
+import "testing"
+
+func TestChecksums(t *testing.T) {
+	testEval(t, []evalTest{
+		{`"123456789" crc32 hex`, "0xcbf43926"},
+		{`"123456789" crc16 hex`, "0xbb3d"},
+		{`"123456789" adler32 hex`, "0x91e01de"},
+		{`"abcde" fletcher hex`, "0xc8f0"},
+		{`"abcdef" fletcher hex`, "0x2057"},
+		{`0x4500 0x0073 0 0x4000 0x4011 0 0xc0a8 1 0xc0a8 0xc7 ">10H" pack inetsum hex`, "0xb861"},
+		{`"0001f203f4f5f6f7" hex> inetsum hex`, "0x220d"},
+		{`"" fnv hex`, "0x811c9dc5"},
+		{`"a" fnv hex`, "0xe40c292c"},
+		{`"a" fnv64 hex`, "0xaf63dc4c8601ec8c"},
+		{`"" md5 >hex`, `"d41d8cd98f00b204e9800998ecf8427e"`},
+		{`"abc" sha1 >hex`, `"a9993e364706816aba3e25717850c26c9cd0d89d"`},
+		{`"abc" sha256 >hex`, `"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"`},
+	})
+}
+
+func TestEncodings(t *testing.T) {
+	testEval(t, []evalTest{
+		{`"cafe" hex>`, "<ca fe>"},
+		{`"CAFE" hex> >hex`, `"cafe"`},
+		{`"hi" >base64`, `"aGk="`},
+		{`"aGk=" base64>`, `"hi"`},
+		{`"hi" >base32`, `"NBUQ===="`},
+		{`"NBUQ====" base32>`, `"hi"`},
+		{`"0001ff" hex> >base64 base64>`, "<00 01 ff>"},
+	})
+	for _, line := range []string{`"caf" hex>`, `"zz" hex>`, `"a" base64>`, `"1" base32>`, "1 crc32", "1 >hex"} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
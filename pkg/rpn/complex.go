@@ -0,0 +1,382 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+)
+
+// bigComplex is a complex number made of two big.Floats. The functions
+// below build on the real ones of bigmath.go through the usual identities.
+type bigComplex struct {
+	re, im *big.Float
+}
+
+// parseComplex parses literals like 3+4i, -2.5e3-1i and 4i.
+func (in *Interpreter) parseComplex(s string) (Var, bool) {
+	if !strings.HasSuffix(s, "i") {
+		return Var{}, false
+	}
+	body := s[:len(s)-1]
+	// split before the sign of the imaginary part, skipping exponent signs
+	k := strings.LastIndexAny(body, "+-")
+	for k > 0 && strings.ContainsAny(body[k-1:k], "eEpP") {
+		k = strings.LastIndexAny(body[:k], "+-")
+	}
+	re, im := "0", body
+	if k > 0 {
+		re, im = body[:k], body[k:]
+	}
+	if im == "" || im == "+" || im == "-" {
+		im += "1"
+	}
+	r, _, err := in.newFloat().Parse(re, 0)
+	if err != nil {
+		return Var{}, false
+	}
+	i, _, err := in.newFloat().Parse(im, 0)
+	if err != nil {
+		return Var{}, false
+	}
+	return Var{Type: Complex, F: r, I: i}, true
+}
+
+// toComplex returns the value of a Complex, Number or Rational.
+func (in *Interpreter) toComplex(v Var) (bigComplex, error) {
+	switch v.Type {
+	case Complex:
+		return bigComplex{v.F, v.I}, nil
+	case Number, Rational:
+		f, err := in.toFloat(v)
+		return bigComplex{f, new(big.Float)}, err
+	}
+	return bigComplex{}, ErrType{Want: Complex, Got: v.Type}
+}
+
+func (in *Interpreter) complex(z bigComplex) []Var {
+	return []Var{{Type: Complex, F: in.round(z.re), I: in.round(z.im)}}
+}
+
+// complex1 and complex2 compute with f when an operand is Complex, and fall
+// back to o otherwise.
+func complex1(f func(z bigComplex, prec uint) (bigComplex, error), o op) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type != Complex {
+			return o.fn(in, args)
+		}
+		z, _ := in.toComplex(args[0])
+		r, err := f(z, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return in.complex(r), nil
+	}}
+}
+
+func complex2(f func(z, w bigComplex, prec uint) (bigComplex, error), o op) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type != Complex && args[1].Type != Complex {
+			return o.fn(in, args)
+		}
+		z, err := in.toComplex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		w, err := in.toComplex(args[1])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(z, w, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return in.complex(r), nil
+	}}
+}
+
+// modulus makes abs return the modulus of Complex numbers.
+func modulus(o op) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type != Complex {
+			return o.fn(in, args)
+		}
+		z, _ := in.toComplex(args[0])
+		return number(in.round(cabs(z, in.Prec))), nil
+	}}
+}
+
+// mul is z = a*b, where zero times infinity is zero instead of NaN.
+func mul(z, a, b *big.Float) *big.Float {
+	if a.Sign() == 0 || b.Sign() == 0 {
+		return z.SetInt64(0)
+	}
+	return z.Mul(a, b)
+}
+
+func cadd(z, w bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	return bigComplex{newFloat(wp).Add(z.re, w.re), newFloat(wp).Add(z.im, w.im)}, nil
+}
+
+func csub(z, w bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	return bigComplex{newFloat(wp).Sub(z.re, w.re), newFloat(wp).Sub(z.im, w.im)}, nil
+}
+
+// cmul is (a+bi)(c+di) = (ac-bd) + (ad+bc)i.
+func cmul(z, w bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	re := mul(newFloat(wp), z.re, w.re)
+	re.Sub(re, mul(newFloat(wp), z.im, w.im))
+	im := mul(newFloat(wp), z.re, w.im)
+	im.Add(im, mul(newFloat(wp), z.im, w.re))
+	return bigComplex{re, im}, nil
+}
+
+// cdiv is (a+bi)/(c+di) = ((ac+bd) + (bc-ad)i) / (c²+d²).
+func cdiv(z, w bigComplex, prec uint) (bigComplex, error) {
+	if w.re.Sign() == 0 && w.im.Sign() == 0 {
+		return bigComplex{}, ErrDivisionByZero{}
+	}
+	wp := prec + guardBits
+	d := mul(newFloat(wp), w.re, w.re)
+	d.Add(d, mul(newFloat(wp), w.im, w.im))
+	re := mul(newFloat(wp), z.re, w.re)
+	re.Add(re, mul(newFloat(wp), z.im, w.im))
+	im := mul(newFloat(wp), z.im, w.re)
+	im.Sub(im, mul(newFloat(wp), z.re, w.im))
+	return bigComplex{re.Quo(re, d), im.Quo(im, d)}, nil
+}
+
+func cconj(z bigComplex, prec uint) (bigComplex, error) {
+	return bigComplex{z.re, newFloat(0).Neg(z.im)}, nil
+}
+
+// cabs is the modulus √(a²+b²).
+func cabs(z bigComplex, prec uint) *big.Float {
+	wp := prec + guardBits
+	d := mul(newFloat(wp), z.re, z.re)
+	d.Add(d, mul(newFloat(wp), z.im, z.im))
+	return d.Sqrt(d)
+}
+
+func carg(z bigComplex, prec uint) (*big.Float, error) {
+	return bigAtan2(z.im, z.re, prec)
+}
+
+// csqrt takes the root of whichever of (|z|+a)/2 and (|z|-a)/2 doesn't
+// cancel, and gets the other part from b = 2·re·im.
+func csqrt(z bigComplex, prec uint) (bigComplex, error) {
+	if z.re.Sign() == 0 && z.im.Sign() == 0 {
+		return bigComplex{newFloat(prec), newFloat(prec)}, nil
+	}
+	wp := prec + guardBits
+	r := cabs(z, wp)
+	t := newFloat(wp)
+	if z.re.Sign() >= 0 {
+		t.Add(r, z.re)
+	} else {
+		t.Sub(r, z.re)
+	}
+	t.SetMantExp(t, -1)
+	t.Sqrt(t)
+	o := newFloat(wp).Quo(z.im, t)
+	o.SetMantExp(o, -1)
+	if z.re.Sign() >= 0 {
+		return bigComplex{t, o}, nil
+	}
+	if z.im.Signbit() {
+		t.Neg(t)
+	}
+	return bigComplex{o.Abs(o), t}, nil
+}
+
+// cln is ln|z| + i arg z.
+func cln(z bigComplex, prec uint) (bigComplex, error) {
+	if z.re.Sign() == 0 && z.im.Sign() == 0 {
+		return bigComplex{}, ErrDomain{Value: "0"}
+	}
+	wp := prec + guardBits
+	re, err := bigLn(cabs(z, wp), wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	im, err := carg(z, wp)
+	return bigComplex{re, im}, err
+}
+
+// cexp is e**a (cos b + i sin b).
+func cexp(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	m, err := bigExp(z.re, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	if z.im.Sign() == 0 {
+		return bigComplex{m, newFloat(wp)}, nil
+	}
+	c, err := bigCos(z.im, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	s, _ := bigSin(z.im, wp)
+	return bigComplex{mul(c, m, c), mul(s, m, s)}, nil
+}
+
+// cpow is z**w, by repeated squaring when w is an integer and as
+// e**(w ln z) otherwise.
+func cpow(z, w bigComplex, prec uint) (bigComplex, error) {
+	if w.im.Sign() == 0 && w.re.IsInt() && w.re.MantExp(nil) <= 64 {
+		n, _ := w.re.Int(nil)
+		return cpowInt(z, n, prec)
+	}
+	if z.re.Sign() == 0 && z.im.Sign() == 0 {
+		if w.re.Sign() > 0 {
+			return bigComplex{newFloat(prec), newFloat(prec)}, nil
+		}
+		return bigComplex{}, ErrDivisionByZero{}
+	}
+	wp := prec + guardBits + 32
+	l, err := cln(z, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	l, _ = cmul(l, w, wp)
+	return cexp(l, prec)
+}
+
+// cpowInt returns z**n by repeated squaring.
+func cpowInt(z bigComplex, n *big.Int, prec uint) (bigComplex, error) {
+	wp := prec + guardBits + uint(n.BitLen())
+	r := bigComplex{newFloat(wp).SetInt64(1), newFloat(wp)}
+	sq := z
+	e := new(big.Int).Abs(n)
+	for i := 0; i < e.BitLen(); i++ {
+		if e.Bit(i) == 1 {
+			r, _ = cmul(r, sq, wp)
+		}
+		if i+1 < e.BitLen() {
+			sq, _ = cmul(sq, sq, wp)
+		}
+	}
+	if n.Sign() < 0 {
+		return cdiv(bigComplex{newFloat(wp).SetInt64(1), newFloat(wp)}, r, wp)
+	}
+	return r, nil
+}
+
+// trig returns sin a, cos a, sinh b and cosh b, the parts of the complex
+// trigonometric and hyperbolic functions.
+func trig(a, b *big.Float, wp uint) (sa, ca, sb, cb *big.Float, err error) {
+	if sa, err = bigSin(a, wp); err != nil {
+		return
+	}
+	ca, _ = bigCos(a, wp)
+	sb, _ = bigSinh(b, wp)
+	cb, _ = bigCosh(b, wp)
+	return
+}
+
+// csin is sin a cosh b + i cos a sinh b.
+func csin(z bigComplex, prec uint) (bigComplex, error) {
+	sa, ca, sb, cb, err := trig(z.re, z.im, prec+guardBits)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	return bigComplex{mul(sa, sa, cb), mul(ca, ca, sb)}, nil
+}
+
+// ccos is cos a cosh b - i sin a sinh b.
+func ccos(z bigComplex, prec uint) (bigComplex, error) {
+	sa, ca, sb, cb, err := trig(z.re, z.im, prec+guardBits)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	im := mul(sa, sa, sb)
+	return bigComplex{mul(ca, ca, cb), im.Neg(im)}, nil
+}
+
+func ctan(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	s, err := csin(z, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	c, _ := ccos(z, wp)
+	return cdiv(s, c, wp)
+}
+
+// csinh is sinh a cos b + i cosh a sin b.
+func csinh(z bigComplex, prec uint) (bigComplex, error) {
+	sb, cb, sa, ca, err := trig(z.im, z.re, prec+guardBits)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	return bigComplex{mul(sa, sa, cb), mul(ca, ca, sb)}, nil
+}
+
+// ccosh is cosh a cos b + i sinh a sin b.
+func ccosh(z bigComplex, prec uint) (bigComplex, error) {
+	sb, cb, sa, ca, err := trig(z.im, z.re, prec+guardBits)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	return bigComplex{mul(ca, ca, cb), mul(sa, sa, sb)}, nil
+}
+
+func ctanh(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	s, err := csinh(z, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	c, _ := ccosh(z, wp)
+	return cdiv(s, c, wp)
+}
+
+// casin is -i ln(iz + √(1-z²)).
+func casin(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits + 32
+	one := bigComplex{newFloat(wp).SetInt64(1), newFloat(wp)}
+	z2, _ := cmul(z, z, wp)
+	r, _ := csub(one, z2, wp)
+	r, _ = csqrt(r, wp)
+	iz := bigComplex{newFloat(wp).Neg(z.im), z.re}
+	r, _ = cadd(iz, r, wp)
+	r, err := cln(r, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	return bigComplex{r.im, r.re.Neg(r.re)}, nil
+}
+
+// cacos is π/2 - asin z.
+func cacos(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits
+	r, err := casin(z, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	halfPi := bigPi(wp)
+	halfPi.SetMantExp(halfPi, -1)
+	return bigComplex{r.re.Sub(halfPi, r.re), r.im.Neg(r.im)}, nil
+}
+
+// catan is (i/2) ln((i+z)/(i-z)).
+func catan(z bigComplex, prec uint) (bigComplex, error) {
+	wp := prec + guardBits + 32
+	i := bigComplex{newFloat(wp), newFloat(wp).SetInt64(1)}
+	n, _ := cadd(i, z, wp)
+	d, _ := csub(i, z, wp)
+	q, err := cdiv(n, d, wp)
+	if err != nil {
+		return bigComplex{}, ErrDomain{Value: "±i"}
+	}
+	l, err := cln(q, wp)
+	if err != nil {
+		return bigComplex{}, err
+	}
+	re := newFloat(wp).Sub(newFloat(wp), l.im)
+	re.SetMantExp(re, -1)
+	l.re.SetMantExp(l.re, -1)
+	return bigComplex{re, l.re}, nil
+}
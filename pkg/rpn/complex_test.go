@@ -0,0 +1,34 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestComplex(t *testing.T) {
+	testEval(t, []evalTest{
+		{"3+4i 1-2i +", "4+2i"},
+		{"3+4i 1-2i -", "2+6i"},
+		{"3+4i 1-2i *", "11-2i"},
+		{"11-2i 1-2i /", "3+4i"},
+		{"1i 1i *", "-1+0i"},
+		{"3+4i abs", "5"},
+		{"3+4i conj", "3-4i"},
+		{"1 2 cmplx", "1+2i"},
+		{"3+4i re", "3"},
+		{"3+4i im", "4"},
+		{"1i arg", "1.5707963267948966193"},
+		{"3+4i polar", "5 0.9272952180016122324"},
+		{"-4+0i sqrt", "0+2i"},
+		{"1 2 cmplx 3 +", "4+2i"},
+	})
+}
+
+func TestComplexFunctions(t *testing.T) {
+	// e**(i pi) = -1, up to the rounding of pi
+	in := evalLines(t, "pi 1i * exp")
+	v := in.stack[0]
+	if v.Type != Complex || v.F.Cmp(big.NewFloat(-1)) != 0 || v.I.MantExp(nil) > -60 {
+		t.Errorf("e**(i pi) is %s", in.Format(v))
+	}
+}
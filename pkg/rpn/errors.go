@@ -0,0 +1,199 @@
+package rpn
+
+import "fmt"
+
+// The errors returned by Eval. Op is the lexeme that failed and Pos its
+// position in the evaluated line, counting from 0.
+
+// ErrStackUnderflow is returned when a word needs more items than the stack
+// holds.
+type ErrStackUnderflow struct {
+	Op   string
+	Pos  int
+	Need int
+	Have int
+}
+
+func (e ErrStackUnderflow) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: needs %d items, the stack has %d", e.Op, e.Pos, e.Need, e.Have)
+}
+
+// ErrUnknownWord is returned for words that are neither keywords, macros
+// nor variables.
+type ErrUnknownWord struct {
+	Op  string
+	Pos int
+}
+
+func (e ErrUnknownWord) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: unknown word", e.Op, e.Pos)
+}
+
+// ErrDomain is returned when a word is applied to a value it is not defined
+// for, like the square root of a negative number, or to a Number that was
+// rounded where an exact integer is needed.
+type ErrDomain struct {
+	Op      string
+	Pos     int
+	Value   string
+	Rounded bool // Value is the rounding of the operand
+}
+
+func (e ErrDomain) Error() string {
+	if e.Rounded {
+		return fmt.Sprintf("%q at lexeme %d: the operand was rounded to %s, not an exact integer", e.Op, e.Pos, e.Value)
+	}
+	return fmt.Sprintf("%q at lexeme %d: %s is out of the domain", e.Op, e.Pos, e.Value)
+}
+
+// ErrDivisionByZero is returned by / and % when the divisor is zero.
+type ErrDivisionByZero struct {
+	Op  string
+	Pos int
+}
+
+func (e ErrDivisionByZero) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: division by zero", e.Op, e.Pos)
+}
+
+// ErrType is returned when a word gets an item of the wrong type.
+type ErrType struct {
+	Op   string
+	Pos  int
+	Want Type
+	Got  Type
+}
+
+func (e ErrType) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: expected %v, got %v", e.Op, e.Pos, e.Want, e.Got)
+}
+
+// ErrSyntax is returned for malformed input, like a number that can't be
+// parsed or a macro without a name.
+type ErrSyntax struct {
+	Op  string
+	Pos int
+	Msg string
+}
+
+func (e ErrSyntax) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: %s", e.Op, e.Pos, e.Msg)
+}
+
+// ErrRecursion is returned when macros and quotations nest too deeply, like
+// a macro that calls itself without end.
+type ErrRecursion struct {
+	Op    string
+	Pos   int
+	Depth int
+}
+
+func (e ErrRecursion) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: more than %d nested calls", e.Op, e.Pos, e.Depth)
+}
+
+// ErrNoHistory is returned by undo and redo when there is nothing left to
+// undo or redo.
+type ErrNoHistory struct {
+	Op  string
+	Pos int
+}
+
+func (e ErrNoHistory) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: nothing to %s", e.Op, e.Pos, e.Op)
+}
+
+// ErrFile is returned when a file can't be read or written, or doesn't hold
+// what was expected.
+type ErrFile struct {
+	Op   string
+	Pos  int
+	Name string
+	Line int // the line of the file, for the errors of included files
+	Err  error
+}
+
+func (e ErrFile) Error() string {
+	name := e.Name
+	if e.Line > 0 {
+		name = fmt.Sprintf("%s:%d", e.Name, e.Line)
+	}
+	if e.Op == "" {
+		return fmt.Sprintf("%s: %v", name, e.Err)
+	}
+	return fmt.Sprintf("%q at lexeme %d: %s: %v", e.Op, e.Pos, name, e.Err)
+}
+
+func (e ErrFile) Unwrap() error {
+	return e.Err
+}
+
+// ErrShape is returned when the vectors or matrices of a word don't have the
+// shapes it needs.
+type ErrShape struct {
+	Op   string
+	Pos  int
+	A, B string
+}
+
+func (e ErrShape) Error() string {
+	return fmt.Sprintf("%q at lexeme %d: shapes %s and %s don't match", e.Op, e.Pos, e.A, e.B)
+}
+
+// at fills in the lexeme and position of errors returned by the words. Errors
+// from the body of a macro or quotation already have them, and are kept.
+func at(err error, t Var) error {
+	switch e := err.(type) {
+	case ErrStackUnderflow:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrUnknownWord:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrDomain:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrDivisionByZero:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrType:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrSyntax:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrRecursion:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrNoHistory:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrFile:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	case ErrShape:
+		if e.Op == "" {
+			e.Op, e.Pos = t.V, t.Pos
+		}
+		return e
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package rpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		want error
+	}{
+		{"1 +", ErrStackUnderflow{Op: "+", Pos: 1, Need: 2, Have: 1}},
+		{"nope", ErrUnknownWord{Op: "nope", Pos: 0}},
+		{"1 0 /", ErrDivisionByZero{Op: "/", Pos: 2}},
+		{"-1 fact", ErrDomain{Op: "fact", Pos: 1, Value: "-1"}},
+		{`"a" 1 +`, ErrType{Op: "+", Pos: 2, Want: Number, Got: String}},
+		{"1..2", ErrSyntax{Op: "1..2", Pos: 0, Msg: "not a number"}},
+		{"[ 1", ErrSyntax{Op: "[", Pos: 0, Msg: "no matching ]"}},
+		{"1 ]", ErrSyntax{Op: "]", Pos: 1, Msg: "no matching ["}},
+	}
+	for _, test := range tests {
+		err := newTest().Eval(test.line)
+		if err != test.want {
+			t.Errorf("%q: got %#v, want %#v", test.line, err, test.want)
+		}
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	in := evalLines(t, "macro loop loop")
+	var e ErrRecursion
+	if err := in.Eval("loop"); !errors.As(err, &e) {
+		t.Errorf("got %v, want ErrRecursion", err)
+	}
+}
+
+func TestErrorsRollBack(t *testing.T) {
+	in := evalLines(t, "1 2 x=")
+	if err := in.Eval("hex 3 y= 64 prec 4 5 +"); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Eval("dec 10 prec 7 z= clr 1 +"); err == nil {
+		t.Fatal("1 + on a single item didn't fail")
+	}
+	if got := show(in); got != "0x1 0x9" {
+		t.Errorf("the stack is %s after a failed line, want 0x1 0x9", got)
+	}
+	if _, ok := in.vars["z"]; ok || in.Mode != "hex" || in.Prec != 64 {
+		t.Errorf("a failed line changed the variables, mode or precision: %v %s %d", in.vars, in.Mode, in.Prec)
+	}
+}
@@ -0,0 +1,673 @@
+package rpn
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// opcode tells what an instruction does.
+type opcode int
+
+const (
+	opPush   opcode = iota // push the item
+	opLoad                 // push a variable, or call the macro it holds
+	opStore                // pop into a variable
+	opOp                   // apply a word of ops
+	opWord                 // apply a word of words
+	opCall                 // call a macro
+	opRepeat               // run the body n times
+	opMacro                // define a macro
+	opFile                 // save, load, include or tee the file named by arg
+)
+
+// instr is an instruction of a compiled line. t is the item it was compiled
+// from, kept for the traces and errors.
+type instr struct {
+	code opcode
+	t    Var
+	op   op      // opOp
+	body []instr // opRepeat
+	arg  Var     // opMacro, opFile
+}
+
+// maxDepth bounds the nesting of macro and quotation calls.
+const maxDepth = 1 << 14
+
+// compile turns parsed items into instructions, resolving the words once.
+// Quotations and macro bodies are compiled along with the line.
+func (in *Interpreter) compile(items []Var) ([]instr, error) {
+	prog := make([]instr, 0, len(items))
+	for i := 0; i < len(items); i++ {
+		t := items[i]
+		switch t.Type {
+		case Variable:
+			prog = append(prog, instr{code: opLoad, t: t})
+			continue
+		case Assignment:
+			prog = append(prog, instr{code: opStore, t: t})
+			continue
+		case Code:
+		default:
+			// data, like Numbers and Strings
+			prog = append(prog, instr{code: opPush, t: t})
+			continue
+		}
+		if t.V == "" {
+			// quotations are data until a word runs them
+			body, err := in.compile(t.Code)
+			if err != nil {
+				return nil, err
+			}
+			t.prog = body
+			prog = append(prog, instr{code: opPush, t: t})
+			continue
+		}
+		if o, ok := ops[t.V]; ok {
+			prog = append(prog, instr{code: opOp, t: t, op: o})
+			continue
+		}
+		switch t.V {
+		case "repeat": // Repeat an operation n times, e.g. '3 repeat +'
+			if i+1 >= len(items) {
+				return nil, ErrSyntax{Op: t.V, Pos: t.Pos, Msg: "nothing to repeat"}
+			}
+			body, err := in.compile(items[i+1 : i+2])
+			if err != nil {
+				return nil, err
+			}
+			prog = append(prog, instr{code: opRepeat, t: t, body: body})
+			i++
+		case "macro": // Defines a macro, e.g. 'macro kib 1024 *'
+			if i+1 >= len(items) || items[i+1].V == "" || items[i+1].Type == Number {
+				return nil, ErrSyntax{Op: t.V, Pos: t.Pos, Msg: "the macro has no name"}
+			}
+			code := append([]Var(nil), items[i+2:]...)
+			body, err := in.compile(code)
+			if err != nil {
+				return nil, err
+			}
+			def := Var{Type: Code, V: items[i+1].V, Code: code, prog: body}
+			prog = append(prog, instr{code: opMacro, t: t, arg: def})
+			i = len(items)
+		case "save", "load", "include", "tee":
+			// Parse makes the file name a String
+			if i+1 >= len(items) || items[i+1].Type != String {
+				return nil, ErrSyntax{Op: t.V, Pos: t.Pos, Msg: "missing file name"}
+			}
+			prog = append(prog, instr{code: opFile, t: t, arg: items[i+1]})
+			i++
+		default:
+			if _, ok := words[t.V]; ok {
+				prog = append(prog, instr{code: opWord, t: t})
+			} else {
+				prog = append(prog, instr{code: opCall, t: t})
+			}
+		}
+	}
+	return prog, nil
+}
+
+// compiled returns the instructions of a quotation or macro, compiling the
+// ones made outside of compile, like by the users of the package.
+func (in *Interpreter) compiled(v Var) ([]instr, error) {
+	if v.prog == nil && len(v.Code) > 0 {
+		return in.compile(v.Code)
+	}
+	return v.prog, nil
+}
+
+// run executes prog on the stack. When pos isn't -1 it is the position of
+// the macro being run, which is where its errors are reported.
+func (in *Interpreter) run(prog []instr, pos int) error {
+	if in.depth >= maxDepth {
+		return ErrRecursion{Depth: maxDepth}
+	}
+	in.depth++
+	defer func() { in.depth-- }()
+	for k := range prog {
+		if in.Exit {
+			return nil
+		}
+		ins := &prog[k]
+		t := ins.t
+		if pos != -1 {
+			t.Pos = pos
+		}
+		if in.Debug {
+			fmt.Fprintln(in.Trace, "Evaluating: ", t, "with variables: ", in.vars)
+		}
+		if ins.code != opWord || t.V != "undo" && t.V != "redo" {
+			// the line changed more than undo or redo did
+			in.undone, in.changed = false, true
+		}
+		if err := in.step(ins, t); err != nil {
+			return at(err, t)
+		}
+		if in.Debug {
+			fmt.Fprint(in.Trace, "Evaluated as:")
+			fmt.Fprint(in.Trace, in.stack)
+			fmt.Fprintln(in.Trace, "With variables:", in.vars)
+		}
+	}
+	return nil
+}
+
+// step executes one instruction, t is its item with the position errors are
+// reported at.
+func (in *Interpreter) step(ins *instr, t Var) error {
+	n := len(in.stack)
+	switch ins.code {
+	case opPush:
+		v, _ := in.literal(ins.t)
+		in.stack = append(in.stack, v)
+	case opLoad:
+		v, ok := in.vars[t.V]
+		if !ok {
+			return ErrUnknownWord{Op: t.V, Pos: t.Pos}
+		}
+		if v.Type == Code && v.V != "" {
+			return in.callMacro(v, t)
+		}
+		v.Pos = t.Pos
+		in.stack = append(in.stack, v)
+	case opStore:
+		if n < 1 {
+			return ErrStackUnderflow{Op: t.V + "=", Pos: t.Pos, Need: 1, Have: n}
+		}
+		in.vars[t.V] = in.stack[n-1]
+		in.stack = in.stack[:n-1]
+	case opOp:
+		if n < ins.op.arity {
+			return ErrStackUnderflow{Need: ins.op.arity, Have: n}
+		}
+		call, o := in.call, ins.op
+		if broadcast[t.V] {
+			call = in.elementwise
+		}
+		if w, ok := wraps[t.V]; ok && in.WordSize > 0 {
+			o = w.wrapped(o)
+		}
+		res, err := call(o, in.stack[n-ins.op.arity:])
+		if err != nil {
+			return err
+		}
+		in.stack = append(in.stack[:n-ins.op.arity], res...)
+	case opWord:
+		if need := words[t.V]; n < need {
+			return ErrStackUnderflow{Need: need, Have: n}
+		}
+		return in.word(t)
+	case opCall:
+		m, ok := in.vars[t.V]
+		if !ok || m.Type != Code {
+			return ErrUnknownWord{}
+		}
+		return in.callMacro(m, t)
+	case opRepeat:
+		if n < 1 {
+			return ErrStackUnderflow{Need: 1, Have: n}
+		}
+		c, err := in.count()
+		if err != nil {
+			return err
+		}
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "for i = 0; i < %v, i++ { %v } \n", c, ins.body[0].t)
+		}
+		for j := 0; j < c && !in.Exit; j++ {
+			if err := in.run(ins.body, t.Pos); err != nil {
+				return err
+			}
+		}
+	case opMacro:
+		if in.Debug {
+			fmt.Fprintln(in.Trace, "Defining macro: ", ins.arg.V, " as ", ins.arg.Code)
+		}
+		in.vars[ins.arg.V] = ins.arg
+		in.keyWords[ins.arg.V] = "x"
+	case opFile:
+		switch t.V {
+		case "save":
+			return in.Save(string(ins.arg.B))
+		case "load":
+			return in.Load(string(ins.arg.B))
+		case "tee":
+			return in.tee(string(ins.arg.B))
+		}
+		return in.include(string(ins.arg.B))
+	}
+	return nil
+}
+
+// callMacro runs the macro m for the word t.
+func (in *Interpreter) callMacro(m, t Var) error {
+	if in.Debug {
+		fmt.Fprintf(in.Trace, "Encountered user-defined macro %v -> %v\n", t.V, m.Code)
+	}
+	prog, err := in.compiled(m)
+	if err != nil {
+		return err
+	}
+	if m.prog == nil {
+		// m is the variable t.V, keep it for the next calls
+		m.prog = prog
+		in.vars[t.V] = m
+	}
+	return in.run(prog, t.Pos)
+}
+
+// call applies o to args, turning the big.ErrNaN panics of operations on
+// infinities, like ∞-∞, into domain errors.
+func (in *Interpreter) call(o op, args []Var) (res []Var, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(big.ErrNaN); !ok {
+				panic(r)
+			}
+			values := make([]string, len(args))
+			for j := range args {
+				values[j], _ = in.formatItem(args[j])
+			}
+			err = ErrDomain{Value: strings.Join(values, " ")}
+		}
+	}()
+	return o.fn(in, args)
+}
+
+// pop removes the top item of the stack.
+func (in *Interpreter) pop() Var {
+	v := in.stack[len(in.stack)-1]
+	in.stack = in.stack[:len(in.stack)-1]
+	return v
+}
+
+func (in *Interpreter) push(vs ...Var) {
+	in.stack = append(in.stack, vs...)
+}
+
+// count pops the top item as a non-negative count.
+func (in *Interpreter) count() (int, error) {
+	n, err := toInt(in.pop())
+	if err != nil {
+		return 0, err
+	}
+	if n.Sign() < 0 || !n.IsInt64() {
+		return 0, ErrDomain{Value: n.String()}
+	}
+	return int(n.Int64()), nil
+}
+
+// quotation pops a quotation like [ 1 + ] and returns its instructions.
+func (in *Interpreter) quotation() ([]instr, error) {
+	v := in.pop()
+	if v.Type == Vector {
+		prog := make([]instr, len(v.Elems))
+		for i := range v.Elems {
+			prog[i] = instr{code: opPush, t: v.Elems[i]}
+		}
+		return prog, nil
+	}
+	if v.Type != Code || v.V != "" {
+		return nil, ErrType{Want: Code, Got: v.Type}
+	}
+	return in.compiled(v)
+}
+
+// truth pops a condition, which holds when it isn't 0.
+func (in *Interpreter) truth() (bool, error) {
+	c, err := sign(in.pop())
+	return c != 0, err
+}
+
+// words are the words that look at the whole stack or run quotations, with
+// the least number of items they need.
+var words = map[string]int{
+	"debug":   0,
+	"cla":     0,
+	"clr":     0,
+	"clv":     0,
+	"hex":     0,
+	"dec":     0,
+	"bin":     0,
+	"oct":     0,
+	"stack":   0,
+	"help":    0,
+	"status":  0,
+	"exit":    0,
+	"depth":   0,
+	"pick":    1,
+	"dropn":   1,
+	"dupn":    1,
+	"roll":    1,
+	"rolld":   1,
+	"call":    1,
+	"if":      2,
+	"ifelse":  3,
+	"while":   2,
+	"times":   2,
+	"for":     3,
+	"join":    2,
+	"format":  1,
+	"undo":    0,
+	"redo":    0,
+	"untee":   0,
+	"predict": 1,
+	"pack":    1,
+	"unpack":  2,
+	"subnet":  2,
+}
+
+// word applies one of the words, which may look at the whole stack or run
+// quotations.
+func (in *Interpreter) word(t Var) error {
+	if s, ok := stats[t.V]; ok {
+		return in.stat(s)
+	}
+	switch t.V {
+	case "debug":
+		fmt.Fprintf(in.Trace, "Toggling debug mode\n")
+		in.Debug = !in.Debug
+	case "cla": // Clear the stack and variables
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "Clearing stack and variables\n")
+		}
+		in.vars = make(map[string]Var, 0)
+		in.stack = in.stack[:0]
+	case "clr": // Clear the stack
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "Clearing the stack\n")
+		}
+		in.stack = in.stack[:0]
+	case "clv": // Clear the variables
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "Clearing the variables\n")
+		}
+		in.vars = make(map[string]Var, 0)
+	case "hex", "dec", "bin", "oct": // Switch display mode
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "mode changed to %s\n", t.V)
+		}
+		in.Mode = t.V
+	case "stack": // Toggles stack display from horizontal to vertical
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "toggle stack visualization\n")
+		}
+		in.Vertical = !in.Vertical
+	case "help": // Print the help message
+		in.help()
+	case "status": // Print the display mode, precision and rounding mode
+		in.status(len(in.stack))
+	case "exit": // Exit the calculator
+		in.Exit = true
+	case "undo": // Go back to the state before the last line
+		return in.undo()
+	case "redo": // Go forward to the line undone
+		return in.redo()
+	case "untee": // Stop copying the output to a file
+		in.untee()
+	case "pack": // Pack values into bytes with a format like >HHI
+		format, err := toString(in.pop())
+		if err != nil {
+			return err
+		}
+		order, codes, err := parsePack(format)
+		if err != nil {
+			return err
+		}
+		n := values(codes)
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 1, Have: len(in.stack) + 1}
+		}
+		b, err := in.pack(order, codes, in.stack[len(in.stack)-n:])
+		if err != nil {
+			return err
+		}
+		in.stack = append(in.stack[:len(in.stack)-n], Var{Type: String, B: b})
+	case "unpack": // Unpack bytes with a format like >HHI
+		format, err := toString(in.pop())
+		if err != nil {
+			return err
+		}
+		order, codes, err := parsePack(format)
+		if err != nil {
+			return err
+		}
+		b, err := toString(in.pop())
+		if err != nil {
+			return err
+		}
+		res, err := in.unpack(order, codes, []byte(b))
+		if err != nil {
+			return err
+		}
+		in.push(res...)
+	case "subnet": // Split a network into the networks of prefix n, e.g. '10.0.0.0/24 26 subnet'
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		v := in.pop()
+		ip, prefix, err := toIP(v)
+		if err != nil {
+			return err
+		}
+		if n < prefix || n > 8*len(ip) || n-prefix > maxSubnetBits {
+			return ErrDomain{Value: fmt.Sprint(n)}
+		}
+		network, _ := ipNet(v)
+		first := new(big.Int).SetBytes(network.IP)
+		step := new(big.Int).Lsh(big.NewInt(1), uint(8*len(ip)-n))
+		for k := 0; k < 1<<uint(n-prefix); k++ {
+			sub := address(first, len(ip))
+			sub.Prefix = n
+			in.push(sub)
+			first.Add(first, step)
+		}
+	case "predict": // Run the model of the last fit on x
+		m, ok := in.vars["model"]
+		if !ok || m.Type != Code || m.V != "" {
+			return ErrUnknownWord{Op: "model", Pos: t.Pos}
+		}
+		prog, err := in.compiled(m)
+		if err != nil {
+			return err
+		}
+		return in.run(prog, t.Pos)
+	case "depth": // Push the current stack depth
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "push(len(stack))\n")
+		}
+		in.push(in.integer(big.NewInt(int64(len(in.stack))))...)
+	case "pick": // Pick the -n'th item from the stack, '1 pick' is dup
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "pick(%v)\n", in.stack[len(in.stack)-1])
+		}
+		v := in.stack[len(in.stack)-1]
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return ErrDomain{Value: in.text(v)}
+		}
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 1, Have: len(in.stack) + 1}
+		}
+		in.push(in.stack[len(in.stack)-n])
+	case "dropn": // Drops n items from the stack
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "dropn(stack,%v)\n", in.stack[len(in.stack)-1])
+		}
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 1, Have: len(in.stack) + 1}
+		}
+		in.stack = in.stack[:len(in.stack)-n]
+	case "dupn": // Duplicates the top n stack items in order
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "dupn(stack,%v)\n", in.stack[len(in.stack)-1])
+		}
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 1, Have: len(in.stack) + 1}
+		}
+		in.push(in.stack[len(in.stack)-n:]...)
+	case "roll", "rolld": // Roll the stack upwards or downwards by n
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "rolls the stack by %v\n", in.stack[len(in.stack)-1])
+		}
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		size := len(in.stack)
+		if size == 0 {
+			return nil
+		}
+		n %= size
+		if t.V == "rolld" {
+			n = (size - n) % size
+		}
+		rolled := make([]Var, 0, size)
+		rolled = append(rolled, in.stack[size-n:]...)
+		rolled = append(rolled, in.stack[:size-n]...)
+		in.stack = rolled
+	case "call": // Run a quotation, e.g. '3 [ dup * ] call'
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		return in.run(body, -1)
+	case "if": // Run a quotation if the condition isn't 0, e.g. 'dup 0 < [ -1 * ] if'
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		c, err := in.truth()
+		if err != nil || !c {
+			return err
+		}
+		return in.run(body, -1)
+	case "ifelse": // Run the first quotation if the condition isn't 0, else the second
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		then, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		c, err := in.truth()
+		if err != nil {
+			return err
+		}
+		if c {
+			body = then
+		}
+		return in.run(body, -1)
+	case "while": // Run the body while the condition leaves non 0, e.g. '[ dup 1 > ] [ 2 / ] while'
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		cond, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		for !in.Exit {
+			if err := in.run(cond, -1); err != nil {
+				return err
+			}
+			if len(in.stack) < 1 {
+				return ErrStackUnderflow{Need: 1, Have: 0}
+			}
+			c, err := in.truth()
+			if err != nil || !c {
+				return err
+			}
+			if err := in.run(body, -1); err != nil {
+				return err
+			}
+		}
+	case "times": // Run a quotation n times, e.g. '1 10 [ 2 * ] times'
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		for j := 0; j < n && !in.Exit; j++ {
+			if err := in.run(body, -1); err != nil {
+				return err
+			}
+		}
+	case "for": // Run a quotation for each integer of a range, pushing it first, e.g. '0 1 10 [ + ] for'
+		body, err := in.quotation()
+		if err != nil {
+			return err
+		}
+		to, err := toInt(in.pop())
+		if err != nil {
+			return err
+		}
+		from, err := toInt(in.pop())
+		if err != nil {
+			return err
+		}
+		for j := from; j.Cmp(to) <= 0 && !in.Exit; j = new(big.Int).Add(j, big.NewInt(1)) {
+			in.push(in.integer(j)...)
+			if err := in.run(body, -1); err != nil {
+				return err
+			}
+		}
+	case "join": // Join n strings with a separator, e.g. '"a" "b" 2 "," join'
+		sep, err := toString(in.pop())
+		if err != nil {
+			return err
+		}
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 2, Have: len(in.stack) + 2}
+		}
+		parts := make([]string, n)
+		for j := range parts {
+			if parts[j], err = toString(in.stack[len(in.stack)-n+j]); err != nil {
+				return err
+			}
+		}
+		in.stack = in.stack[:len(in.stack)-n]
+		in.push(str(strings.Join(parts, sep))...)
+	case "format": // Format items with a fmt format, e.g. '3.14159 "%.2f" format'
+		format, err := toString(in.pop())
+		if err != nil {
+			return err
+		}
+		vs, err := verbs(format)
+		if err != nil {
+			return err
+		}
+		n := len(vs)
+		if len(in.stack) < n {
+			return ErrStackUnderflow{Need: n + 1, Have: len(in.stack) + 1}
+		}
+		s, err := in.sprintf(format, in.stack[len(in.stack)-n:])
+		if err != nil {
+			return err
+		}
+		in.stack = in.stack[:len(in.stack)-n]
+		in.push(str(s)...)
+	}
+	return nil
+}
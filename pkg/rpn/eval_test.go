@@ -0,0 +1,67 @@
+package rpn
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestControlFlow(t *testing.T) {
+	testEval(t, []evalTest{
+		{"1 2 [ + ] call", "3"},
+		{"[ 1 + ]", "[ 1 + ]"},
+		{"[ ] call", ""},
+		{"5 dup 0 < [ -1 * ] if", "5"},
+		{"-5 dup 0 < [ -1 * ] if", "5"},
+		{"1 [ 10 ] [ 20 ] ifelse", "10"},
+		{"0 [ 10 ] [ 20 ] ifelse", "20"},
+		{"100 [ dup 1 > ] [ 2 / ] while", "0.78125"},
+		{"1 10 [ 2 * ] times", "1024"},
+		{"0 1 10 [ + ] for", "55"},
+		{"1 2 3 2 repeat +", "6"},
+		{"[ [ 1 ] call 2 ] call", "1 2"},
+	})
+}
+
+func TestQuotationErrors(t *testing.T) {
+	for _, line := range []string{"1 call", "1 [ 2 ] [ 3 ] 4 ifelse", "[ 1 ] 2 while", "1 2 3 repeat +"} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
+
+func TestLongScript(t *testing.T) {
+	in := evalLines(t, script(1e6))
+	if got := show(in); got != "500000" {
+		t.Errorf("got %s, want 500000", got)
+	}
+}
+
+func TestIncludeLongLine(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "long.rpn")
+	if err := ioutil.WriteFile(name, []byte(script(1e5)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in := evalLines(t, "include "+strconv.Quote(name))
+	if got := show(in); got != "50000" {
+		t.Errorf("got %s, want 50000", got)
+	}
+}
+
+func TestLoadedMacrosStayCompiled(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "s.json")
+	evalLines(t, "macro inc 1 +", "save "+strconv.Quote(name))
+	in := evalLines(t, "load "+strconv.Quote(name))
+	if in.vars["inc"].prog == nil {
+		t.Error("load didn't compile the macro")
+	}
+	in.vars["inc"] = Var{Type: Code, V: "inc", Code: in.vars["inc"].Code}
+	if err := in.Eval("1 inc"); err != nil {
+		t.Fatal(err)
+	}
+	if in.vars["inc"].prog == nil {
+		t.Error("calling the macro didn't keep its instructions")
+	}
+}
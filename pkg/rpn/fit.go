@@ -0,0 +1,271 @@
+package rpn
+
+import (
+	"math/big"
+)
+
+// The fitting words take n x y pairs, with n on top, and store the model in
+// the model variable as a quotation, which predict runs.
+
+func init() {
+	stats["linreg"] = stat{n: true, pairs: true, min: 2, fn: fitLinear}
+	stats["polyfit"] = stat{n: true, pairs: true, args: 1, min: 1, fn: fitPoly}
+	stats["expfit"] = stat{n: true, pairs: true, min: 2, fn: fitExp}
+	stats["logfit"] = stat{n: true, pairs: true, min: 2, fn: fitLog}
+	for _, k := range []string{"linreg", "polyfit", "expfit", "logfit"} {
+		words[k] = stats[k].args + 1
+	}
+}
+
+// unzip splits x y pairs.
+func unzip(xys []*big.Float) (xs, ys []*big.Float) {
+	for i := 0; i < len(xys); i += 2 {
+		xs, ys = append(xs, xys[i]), append(ys, xys[i+1])
+	}
+	return xs, ys
+}
+
+// line fits y = slope*x + intercept by least squares.
+func (in *Interpreter) line(xs, ys []*big.Float) (slope, intercept *big.Float, err error) {
+	mx, my := in.mean(xs), in.mean(ys)
+	sxx, sxy, dx, dy := in.wp(), in.wp(), in.wp(), in.wp()
+	for i := range xs {
+		dx.Sub(xs[i], mx)
+		dy.Sub(ys[i], my)
+		sxy.Add(sxy, dy.Mul(dx, dy))
+		sxx.Add(sxx, dx.Mul(dx, dx))
+	}
+	if sxx.Sign() == 0 {
+		return nil, nil, ErrDomain{Value: "x = " + in.round(xs[0]).String()}
+	}
+	slope = in.wp().Quo(sxy, sxx)
+	intercept = in.wp().Mul(slope, mx)
+	return slope, intercept.Sub(my, intercept), nil
+}
+
+// rsquared returns the coefficient of determination of the predictions, 1
+// when the ys don't vary.
+func (in *Interpreter) rsquared(ys, preds []*big.Float) *big.Float {
+	my := in.mean(ys)
+	res, tot, d := in.wp(), in.wp(), in.wp()
+	for i := range ys {
+		d.Sub(ys[i], preds[i])
+		res.Add(res, d.Mul(d, d))
+		d.Sub(ys[i], my)
+		tot.Add(tot, d.Mul(d, d))
+	}
+	if tot.Sign() == 0 {
+		return big.NewFloat(1)
+	}
+	res.Quo(res, tot)
+	return res.Sub(big.NewFloat(1), res)
+}
+
+// horner returns the value at x of the polynomial with coefficients cs,
+// highest degree first.
+func (in *Interpreter) horner(cs []*big.Float, x *big.Float) *big.Float {
+	z := in.wp()
+	for _, c := range cs {
+		z.Mul(z, x).Add(z, c)
+	}
+	return z
+}
+
+func (in *Interpreter) predictions(cs, xs []*big.Float) []*big.Float {
+	preds := make([]*big.Float, len(xs))
+	for i := range xs {
+		preds[i] = in.horner(cs, xs[i])
+	}
+	return preds
+}
+
+// keyword returns a keyword item of a quotation.
+func keyword(name string) Var {
+	return Var{Type: Code, V: name}
+}
+
+// setModel stores the model quotation made of items, the numbers rounded to
+// the interpreter's precision.
+func (in *Interpreter) setModel(items ...interface{}) {
+	var code []Var
+	for _, it := range items {
+		switch v := it.(type) {
+		case *big.Float:
+			code = append(code, number(in.round(v))...)
+		case string:
+			code = append(code, keyword(v))
+		}
+	}
+	prog, _ := in.compile(code)
+	in.vars["model"] = Var{Type: Code, Code: code, prog: prog}
+}
+
+// fitLinear pushes the slope, intercept and r² of the least squares line.
+func fitLinear(in *Interpreter, data []Var, xys []*big.Float, args []Var) ([]Var, error) {
+	xs, ys := unzip(xys)
+	slope, intercept, err := in.line(xs, ys)
+	if err != nil {
+		return nil, err
+	}
+	r2 := in.rsquared(ys, in.predictions([]*big.Float{slope, intercept}, xs))
+	in.setModel(slope, "*", intercept, "+")
+	return in.results(slope, intercept, r2), nil
+}
+
+// fitPoly pushes the coefficients of the least squares polynomial of degree
+// d, highest degree first, and its r². It solves the normal equations
+// exactly, by Gaussian elimination on rationals.
+func fitPoly(in *Interpreter, data []Var, xys []*big.Float, args []Var) ([]Var, error) {
+	d, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	xs, ys := unzip(xys)
+	if d.Sign() < 0 || d.Cmp(big.NewInt(int64(len(xs)))) >= 0 {
+		return nil, ErrDomain{Value: d.String()}
+	}
+	m := int(d.Int64()) + 1
+	// sums[k] is the sum of the x**k, the row of a coefficient is its
+	// equation followed by the sum of the y x**k
+	sums := make([]*big.Rat, 2*m-1)
+	rows := make([][]*big.Rat, m)
+	for k := range sums {
+		sums[k] = new(big.Rat)
+	}
+	for i := range rows {
+		rows[i] = make([]*big.Rat, m+1)
+		rows[i][m] = new(big.Rat)
+	}
+	for i := range xs {
+		if xs[i].IsInf() || ys[i].IsInf() {
+			return nil, ErrDomain{Value: "∞"}
+		}
+		x, _ := xs[i].Rat(nil)
+		y, _ := ys[i].Rat(nil)
+		p := big.NewRat(1, 1)
+		for k := range sums {
+			sums[k].Add(sums[k], p)
+			if k < m {
+				rows[k][m].Add(rows[k][m], new(big.Rat).Mul(y, p))
+			}
+			p.Mul(p, x)
+		}
+	}
+	for i := range rows {
+		for j := 0; j < m; j++ {
+			rows[i][j] = new(big.Rat).Set(sums[i+j])
+		}
+	}
+	for col := 0; col < m; col++ {
+		pivot := col
+		for pivot < m && rows[pivot][col].Sign() == 0 {
+			pivot++
+		}
+		if pivot == m {
+			return nil, ErrDomain{Value: d.String()}
+		}
+		rows[col], rows[pivot] = rows[pivot], rows[col]
+		for r := col + 1; r < m; r++ {
+			f := new(big.Rat).Quo(rows[r][col], rows[col][col])
+			for j := col; j <= m; j++ {
+				rows[r][j].Sub(rows[r][j], new(big.Rat).Mul(f, rows[col][j]))
+			}
+		}
+	}
+	// back substitution, the coefficients highest degree first
+	cs := make([]*big.Float, m)
+	rs := make([]*big.Rat, m)
+	for i := m - 1; i >= 0; i-- {
+		z := new(big.Rat).Set(rows[i][m])
+		for j := i + 1; j < m; j++ {
+			z.Sub(z, new(big.Rat).Mul(rows[i][j], rs[j]))
+		}
+		rs[i] = z.Quo(z, rows[i][i])
+		cs[m-1-i] = in.wp().SetRat(rs[i])
+	}
+	r2 := in.rsquared(ys, in.predictions(cs, xs))
+	in.setModel(polyModel(cs)...)
+	return in.results(append(cs, r2)...), nil
+}
+
+// polyModel returns the items of a quotation computing the polynomial with
+// coefficients cs, highest degree first, of the x on the stack.
+func polyModel(cs []*big.Float) []interface{} {
+	d := len(cs) - 1
+	switch d {
+	case 0:
+		return []interface{}{"drop", cs[0]}
+	case 1:
+		return []interface{}{cs[0], "*", cs[1], "+"}
+	}
+	// x → c_d x**d x → ... → c_d x**d ... c_1 x x → c_d x**d ... c_0, summed
+	var items []interface{}
+	for k := d; k >= 1; k-- {
+		items = append(items, "dup", new(big.Float).SetInt64(int64(k)), "**", cs[d-k], "*", "swap")
+	}
+	items = append(items, "drop", cs[d])
+	for k := 0; k < d; k++ {
+		items = append(items, "+")
+	}
+	return items
+}
+
+// fitExp pushes a, b and r² of y = a e**(bx), fitting ln y with a line, the
+// r² is the line's.
+func fitExp(in *Interpreter, data []Var, xys []*big.Float, args []Var) ([]Var, error) {
+	xs, ys := unzip(xys)
+	lys := make([]*big.Float, len(ys))
+	for i := range ys {
+		if ys[i].Sign() <= 0 {
+			return nil, ErrDomain{Value: in.round(ys[i]).String()}
+		}
+		l, err := bigLn(ys[i], in.Prec+guardBits)
+		if err != nil {
+			return nil, err
+		}
+		lys[i] = l
+	}
+	b, la, err := in.line(xs, lys)
+	if err != nil {
+		return nil, err
+	}
+	a, err := bigExp(la, in.Prec+guardBits)
+	if err != nil {
+		return nil, err
+	}
+	r2 := in.rsquared(lys, in.predictions([]*big.Float{b, la}, xs))
+	in.setModel(b, "*", "exp", a, "*")
+	return in.results(a, b, r2), nil
+}
+
+// fitLog pushes a, b and r² of y = a + b ln x.
+func fitLog(in *Interpreter, data []Var, xys []*big.Float, args []Var) ([]Var, error) {
+	xs, ys := unzip(xys)
+	lxs := make([]*big.Float, len(xs))
+	for i := range xs {
+		if xs[i].Sign() <= 0 {
+			return nil, ErrDomain{Value: in.round(xs[i]).String()}
+		}
+		l, err := bigLn(xs[i], in.Prec+guardBits)
+		if err != nil {
+			return nil, err
+		}
+		lxs[i] = l
+	}
+	b, a, err := in.line(lxs, ys)
+	if err != nil {
+		return nil, err
+	}
+	r2 := in.rsquared(ys, in.predictions([]*big.Float{b, a}, lxs))
+	in.setModel("ln", b, "*", a, "+")
+	return in.results(a, b, r2), nil
+}
+
+// results rounds xs to the interpreter's precision.
+func (in *Interpreter) results(xs ...*big.Float) []Var {
+	res := make([]Var, len(xs))
+	for i := range xs {
+		res[i] = Var{Type: Number, F: in.round(xs[i])}
+	}
+	return res
+}
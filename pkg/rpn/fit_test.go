@@ -0,0 +1,45 @@
+package rpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	testEval(t, []evalTest{
+		{"1 3 2 5 3 7 3 linreg", "2 1 1"},
+		{"1 3 2 5 3 7 3 linreg 10 predict", "2 1 1 21"},
+		{"9 1 3 2 5 2 linreg", "9 2 1 1"},
+		{"0 1 1 2 2 5 3 10 2 4 polyfit", "1 0 1 1"},
+		{"0 1 1 2 2 5 3 10 2 4 polyfit 4 predict", "1 0 1 1 17"},
+		{"1 3 2 5 0 2 polyfit 7 predict", "4 0 4"},
+		{"0 1 1 2 2 4 3 expfit 3 predict", "1 0.69314718055994530943 1 8"},
+		{"1 0 2 2 2 logfit", "0 2.8853900817779268148 1"},
+	})
+}
+
+func TestFitErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		want error
+	}{
+		{"4611686018427387904 linreg", ErrDomain{Op: "linreg", Pos: 1, Value: "4611686018427387904"}},
+		{"1 2 3 4 3 linreg", ErrStackUnderflow{Op: "linreg", Pos: 5, Need: 7, Have: 5}},
+		{"1 2 3 4 1 9223372036854775807 polyfit", ErrDomain{Op: "polyfit", Pos: 6, Value: "9223372036854775807"}},
+		{"1 2 1 linreg", ErrDomain{Op: "linreg", Pos: 3, Value: "1"}},
+		{"1 2 1 2 2 linreg", ErrDomain{Op: "linreg", Pos: 5, Value: "x = 1"}},
+		{"1 1 2 0 2 expfit", ErrDomain{Op: "expfit", Pos: 5, Value: "0"}},
+		{"0 1 1 2 2 logfit", ErrDomain{Op: "logfit", Pos: 5, Value: "0"}},
+		{"1 1 2 2 2 2 polyfit", ErrDomain{Op: "polyfit", Pos: 6, Value: "2"}},
+	}
+	for _, test := range tests {
+		err := newTest().Eval(test.line)
+		if err != test.want {
+			t.Errorf("%q: got %#v, want %#v", test.line, err, test.want)
+		}
+	}
+	var e ErrUnknownWord
+	if err := newTest().Eval("1 predict"); !errors.As(err, &e) {
+		t.Errorf("predict without a model: got %v", err)
+	}
+}
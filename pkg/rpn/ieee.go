@@ -0,0 +1,248 @@
+package rpn
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ieee is an IEEE-754 binary interchange format.
+type ieee struct {
+	exp, mant uint // bits of the exponent and of the stored mantissa
+}
+
+// The formats of the words, named like the words.
+var (
+	f16  = ieee{5, 10}
+	bf16 = ieee{8, 7}
+	f32  = ieee{8, 23}
+	f64  = ieee{11, 52}
+)
+
+func (f ieee) bias() int { return 1<<(f.exp-1) - 1 }
+
+func (f ieee) size() uint { return 1 + f.exp + f.mant }
+
+// encode returns the bits of x rounded to nearest even in the format,
+// overflowing to infinity and underflowing to the subnormals and zero.
+func (f ieee) encode(x *big.Float) *big.Int {
+	if x.IsInf() {
+		bits := new(big.Int).Lsh(big.NewInt(1<<f.exp-1), f.mant)
+		if x.Signbit() {
+			bits.SetBit(bits, int(f.size()-1), 1)
+		}
+		return bits
+	}
+	r, _ := x.Rat(nil)
+	return f.encodeRat(r, x.Signbit())
+}
+
+// encodeRat returns the bits of r, negative when neg, which keeps the sign
+// of zeros, rounding it once.
+func (f ieee) encodeRat(r *big.Rat, neg bool) *big.Int {
+	bits := new(big.Int)
+	if neg {
+		bits.SetBit(bits, int(f.size()-1), 1)
+	}
+	if r.Sign() == 0 {
+		return bits
+	}
+	a := new(big.Rat).Abs(r)
+	// 2**e <= a < 2**(e+1)
+	e := a.Num().BitLen() - a.Denom().BitLen()
+	if a.Cmp(pow2(e)) < 0 {
+		e--
+	}
+	emin := 1 - f.bias()
+	if e < emin {
+		// subnormal, a multiple of 2**(emin-mant), which may round up to
+		// the smallest normal number, whose bits follow
+		return bits.Or(bits, roundEven(a.Mul(a, pow2(int(f.mant)-emin))))
+	}
+	m := roundEven(a.Mul(a, pow2(int(f.mant)-e)))
+	if m.BitLen() > int(f.mant)+1 {
+		// rounded up to 2**(e+1)
+		m.Rsh(m, 1)
+		e++
+	}
+	if e > f.bias() {
+		return bits.Or(bits, new(big.Int).Lsh(big.NewInt(1<<f.exp-1), f.mant))
+	}
+	m.SetBit(m, int(f.mant), 0)
+	bits.Or(bits, m)
+	return bits.Or(bits, new(big.Int).Lsh(big.NewInt(int64(e+f.bias())), f.mant))
+}
+
+// encodeVar returns the bits of a Number or Rational. Numbers parsed from a
+// literal are rounded from its decimal, not from their own rounding of it.
+func (f ieee) encodeVar(in *Interpreter, v Var) (*big.Int, error) {
+	switch {
+	case v.Type == Rational:
+		return f.encodeRat(v.R, v.R.Sign() < 0), nil
+	case v.Type == Number && v.lit != "" && !v.F.IsInf():
+		r, ok := new(big.Rat).SetString(v.lit)
+		if ok && newFloat(v.F.Prec()).SetMode(v.F.Mode()).SetRat(r).Cmp(v.F) == 0 {
+			return f.encodeRat(r, v.F.Signbit()), nil
+		}
+	}
+	x, err := in.toFloat(v)
+	if err != nil {
+		return nil, err
+	}
+	return f.encode(x), nil
+}
+
+// pow2 returns 2**e.
+func pow2(e int) *big.Rat {
+	if e < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), uint(-e)))
+	}
+	return new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(e)))
+}
+
+// roundEven rounds y >= 0 to the nearest integer, to even on ties.
+func roundEven(y *big.Rat) *big.Int {
+	i, rem := new(big.Int).QuoRem(y.Num(), y.Denom(), new(big.Int))
+	if c := rem.Lsh(rem, 1).Cmp(y.Denom()); c > 0 || c == 0 && i.Bit(0) == 1 {
+		i.Add(i, big.NewInt(1))
+	}
+	return i
+}
+
+// parts splits bits into the sign, the biased exponent and the stored
+// mantissa.
+func (f ieee) parts(bits *big.Int) (sign, exp, mant *big.Int) {
+	mask := func(n uint) *big.Int { return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), n), big.NewInt(1)) }
+	mant = new(big.Int).And(bits, mask(f.mant))
+	exp = new(big.Int).Rsh(bits, f.mant)
+	exp.And(exp, mask(f.exp))
+	sign = big.NewInt(int64(bits.Bit(int(f.size() - 1))))
+	return sign, exp, mant
+}
+
+// decode returns the value of bits, which can't be a NaN.
+func (f ieee) decode(bits *big.Int) (*big.Float, error) {
+	sign, exp, mant := f.parts(bits)
+	z := newFloat(f.mant + 1)
+	switch e := int(exp.Int64()); {
+	case e == 1<<f.exp-1:
+		if mant.Sign() != 0 {
+			return nil, ErrDomain{Value: "NaN"}
+		}
+		z.SetInf(false)
+	case e == 0:
+		z.SetMantExp(z.SetInt(mant), 1-f.bias()-int(f.mant))
+	default:
+		mant.SetBit(mant, int(f.mant), 1)
+		z.SetMantExp(z.SetInt(mant), e-f.bias()-int(f.mant))
+	}
+	if sign.Sign() != 0 {
+		z.Neg(z)
+	}
+	return z, nil
+}
+
+// exact returns a value of the format with the interpreter's precision, when
+// it holds it exactly, so that it prints with all its digits.
+func (f ieee) exact(in *Interpreter, z *big.Float) *big.Float {
+	if in.Prec <= f.mant {
+		return z
+	}
+	return in.newFloat().Set(z)
+}
+
+// bitsOf returns the bits of a format in an integer, which may be negative
+// as with a signed word size.
+func (f ieee) bitsOf(v Var) (*big.Int, error) {
+	i, err := toInt(v)
+	if err != nil {
+		return nil, err
+	}
+	lim := new(big.Int).Lsh(big.NewInt(1), f.size())
+	if i.Cmp(lim) >= 0 || new(big.Int).Neg(i).Cmp(new(big.Int).Rsh(lim, 1)) > 0 {
+		return nil, ErrDomain{Value: i.String()}
+	}
+	if i.Sign() < 0 {
+		i.Add(i, lim)
+	}
+	return i, nil
+}
+
+// toBits returns the op of f16>bits and the like.
+func (f ieee) toBits() op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		bits, err := f.encodeVar(in, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(bits), nil
+	}}
+}
+
+// fromBits returns the op of bits>f16 and the like.
+func (f ieee) fromBits() op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		bits, err := f.bitsOf(args[0])
+		if err != nil {
+			return nil, err
+		}
+		z, err := f.decode(bits)
+		if err != nil {
+			return nil, err
+		}
+		return number(f.exact(in, z)), nil
+	}}
+}
+
+// rounding returns the op of >f16 and the like.
+func (f ieee) rounding() op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		bits, err := f.encodeVar(in, args[0])
+		if err != nil {
+			return nil, err
+		}
+		z, err := f.decode(bits)
+		if err != nil {
+			return nil, err
+		}
+		return number(f.exact(in, z)), nil
+	}}
+}
+
+// toParts returns the op of f16>parts and the like.
+func (f ieee) toParts() op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		bits, err := f.encodeVar(in, args[0])
+		if err != nil {
+			return nil, err
+		}
+		sign, exp, mant := f.parts(bits)
+		return append(append(in.integer(sign), in.integer(exp)...), in.integer(mant)...), nil
+	}}
+}
+
+// toStr returns the op of f16>str and the like.
+func (f ieee) toStr() op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		bits, err := f.encodeVar(in, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return str(f.describe(bits)), nil
+	}}
+}
+
+// describe breaks bits down like
+// "sign 0 exponent 10000000 (1) mantissa 10010010000111111011011 = 3.1415927".
+func (f ieee) describe(bits *big.Int) string {
+	sign, exp, mant := f.parts(bits)
+	value := "NaN"
+	if z, err := f.decode(bits); err == nil {
+		value = z.Text('g', -1)
+	}
+	e := int(exp.Int64()) - f.bias()
+	if exp.Sign() == 0 {
+		e = 1 - f.bias()
+	}
+	return fmt.Sprintf("sign %d exponent %0*b (%d) mantissa %0*b = %s",
+		sign, f.exp, exp, e, f.mant, mant, value)
+}
@@ -0,0 +1,63 @@
+package rpn
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestIEEE(t *testing.T) {
+	testEval(t, []evalTest{
+		{"1.5 f16>bits hex", "0x3e00"},
+		{"0x3e00 bits>f16", "1.5"},
+		{"0.1 f32>bits hex", "0x3dcccccd"},
+		{"0.1 >f32", "0.100000001490116119385"},
+		{"1/3 f64>bits hex", "0x3fd5555555555555"},
+		{"-0.0 f64>bits hex", "0x8000000000000000"},
+		{"1e400 f64>bits hex", "0x7ff0000000000000"},
+		{"0x7ff0000000000000 bits>f64", "+Inf"},
+		{"65519 f16>bits hex", "0x7bff"},
+		{"65520 f16>bits hex", "0x7c00"},
+		{"2.9802322387695312e-8 f16>bits", "0"},
+		{"2.9802322387695313e-8 f16>bits", "1"},
+		{"1 bf16>bits hex", "0x3f80"},
+		{"1.5 f16>parts", "0 15 512"},
+		{"0.003969948147109647 f64>bits hex", "0x3f7042cad756a333"},
+		{"0.003969948147109647 \">d\" pack", "<3f 70 42 ca d7 56 a3 33>"},
+		{"0x8000 bits>f16", "-0"},
+	})
+}
+
+// TestIEEERounding compares the bits of decimals, rounded once from their
+// digits, with strconv's.
+func TestIEEERounding(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		s := fmt.Sprintf("%d.%017de%d", r.Intn(10), r.Int63n(1e17), r.Intn(700)-350)
+		x64, _ := strconv.ParseFloat(s, 64)
+		x32, _ := strconv.ParseFloat(s, 32)
+		for _, test := range []struct {
+			word string
+			want uint64
+		}{
+			{"f64>bits", math.Float64bits(x64)},
+			{"f32>bits", uint64(math.Float32bits(float32(x32)))},
+		} {
+			in := evalLines(t, s+" "+test.word)
+			if got, acc := in.stack[0].F.Uint64(); acc != big.Exact || got != test.want {
+				t.Errorf("%s %s: got %#x, want %#x", s, test.word, got, test.want)
+			}
+		}
+	}
+}
+
+func TestIEEEErrors(t *testing.T) {
+	for _, line := range []string{"0x7e00 bits>f16", "0x10000 bits>f16", `"a" f32>bits`, "3+4i f64>bits"} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
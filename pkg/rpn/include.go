@@ -0,0 +1,69 @@
+package rpn
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxLine is the longest line, in bytes, read from included files and by
+// the rpn command, long enough for generated scripts of millions of words.
+const MaxLine = 1 << 30
+
+// include evaluates the lines of the file name as part of the current line,
+// so an error anywhere in it undoes the whole file. Blank lines and lines
+// starting with # are skipped. Relative names in an included file are
+// relative to its directory.
+func (in *Interpreter) include(name string) error {
+	path := expandHome(name)
+	if n := len(in.includes); n > 0 && !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(in.includes[n-1]), path)
+	}
+	for _, f := range in.includes {
+		if f == path {
+			return ErrFile{Name: name, Err: errors.New("included from itself")}
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	defer f.Close()
+	in.includes = append(in.includes, path)
+	defer func() { in.includes = in.includes[:len(in.includes)-1] }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, MaxLine)
+	for line := 1; scanner.Scan() && !in.Exit; line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := in.evalIncluded(text); err != nil {
+			return ErrFile{Name: name, Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	return nil
+}
+
+// evalIncluded runs a line of an included file on the current state.
+func (in *Interpreter) evalIncluded(line string) error {
+	lex, err := Lex(line)
+	if err != nil {
+		return err
+	}
+	items, err := in.Parse(lex)
+	if err != nil {
+		return err
+	}
+	prog, err := in.compile(items)
+	if err != nil {
+		return err
+	}
+	return in.run(prog, -1)
+}
@@ -0,0 +1,59 @@
+package rpn
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// writeFiles writes the files of a directory, by name.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.rpn":      "# a library\n\nmacro kib 1024 *\ninclude lib/units.rpn\n",
+		"lib/units.rpn": "macro mib kib kib\n1 x=\n",
+	})
+	in := evalLines(t, "include "+strconv.Quote(filepath.Join(dir, "main.rpn")), "2 mib 1024 / x")
+	if got := show(in); got != "2048 1" {
+		t.Errorf("got %s, want 2048 1", got)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"bad.rpn":  "1 x=\n\n2 nope\n",
+		"self.rpn": "include self.rpn\n",
+	})
+	in := evalLines(t, "5")
+	err := in.Eval("include " + strconv.Quote(filepath.Join(dir, "bad.rpn")))
+	var e ErrFile
+	if !errors.As(err, &e) || e.Line != 3 {
+		t.Errorf("got %v, want an error at line 3", err)
+	}
+	if _, ok := in.vars["x"]; ok || show(in) != "5" {
+		t.Error("the failed include wasn't undone")
+	}
+	if err := in.Eval("include " + strconv.Quote(filepath.Join(dir, "self.rpn"))); err == nil {
+		t.Error("a file including itself didn't fail")
+	}
+	if err := in.Eval("include " + strconv.Quote(filepath.Join(dir, "missing.rpn"))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v for a missing file", err)
+	}
+}
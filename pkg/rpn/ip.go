@@ -0,0 +1,98 @@
+package rpn
+
+import (
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// An IP holds an IPv4 or IPv6 address in B, 4 or 16 bytes, and the length of
+// its network prefix, or -1 for a single address. They are written like
+// 192.168.1.7, 192.168.1.0/24 and 2001:db8::/32.
+
+// parseIP parses an address or a network in CIDR notation, keeping the host
+// bits of the address.
+func parseIP(s string) (Var, bool) {
+	addr, prefix := s, -1
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || s[i+1:] == "" || s[i+1] == '+' || s[i+1] == '-' {
+			return Var{}, false
+		}
+		addr, prefix = s[:i], n
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return Var{}, false
+	}
+	if v4 := ip.To4(); v4 != nil && !strings.Contains(addr, ":") {
+		ip = v4
+	}
+	if prefix > 8*len(ip) {
+		return Var{}, false
+	}
+	return Var{Type: IP, B: ip, Prefix: prefix}, true
+}
+
+// looksIP tells the lexemes that may be addresses, like 10.0.0.1 or ::1,
+// from numbers and words.
+func looksIP(s string) bool {
+	return strings.Count(s, ".") == 3 && s[0] >= '0' && s[0] <= '9' || strings.Contains(s, ":")
+}
+
+// formatIP formats an address in dotted or colon notation, IPv4-mapped
+// IPv6 addresses like ::ffff:1.2.3.4.
+func formatIP(v Var) string {
+	s := net.IP(v.B).String()
+	if len(v.B) == net.IPv6len && net.IP(v.B).To4() != nil {
+		s = "::ffff:" + s
+	}
+	if v.Prefix >= 0 {
+		s += "/" + strconv.Itoa(v.Prefix)
+	}
+	return s
+}
+
+// toIP returns an IP and the length of its prefix, which is the whole
+// address for single addresses.
+func toIP(v Var) (net.IP, int, error) {
+	if v.Type != IP {
+		return nil, 0, ErrType{Want: IP, Got: v.Type}
+	}
+	if v.Prefix < 0 {
+		return v.B, 8 * len(v.B), nil
+	}
+	return v.B, v.Prefix, nil
+}
+
+// ipNet returns the network of an IP.
+func ipNet(v Var) (*net.IPNet, error) {
+	ip, prefix, err := toIP(v)
+	if err != nil {
+		return nil, err
+	}
+	mask := net.CIDRMask(prefix, 8*len(ip))
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// address returns an IP for the integer i, of the size of an address of
+// size bytes.
+func address(i *big.Int, size int) Var {
+	b := i.Bytes()
+	return Var{Type: IP, B: append(make([]byte, size-len(b)), b...), Prefix: -1}
+}
+
+// lastIP returns the last address of a network, its broadcast address in
+// IPv4.
+func lastIP(n *net.IPNet) net.IP {
+	ip := make(net.IP, len(n.IP))
+	for i := range ip {
+		ip[i] = n.IP[i] | ^n.Mask[i]
+	}
+	return ip
+}
+
+// maxSubnetBits bounds the networks pushed by subnet to 2**maxSubnetBits,
+// the prefix growing by at most that many bits.
+const maxSubnetBits = 12
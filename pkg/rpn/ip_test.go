@@ -0,0 +1,47 @@
+package rpn
+
+import "testing"
+
+func TestIP(t *testing.T) {
+	testEval(t, []evalTest{
+		{"192.168.1.7", "192.168.1.7"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"::ffff:1.2.3.4", "::ffff:1.2.3.4"},
+		{"::ffff:1.2.3.4/120 network", "::ffff:1.2.3.0/120"},
+		{"192.168.1.77/24 netmask", "255.255.255.0"},
+		{"192.168.1.77/24 network", "192.168.1.0/24"},
+		{"192.168.1.77/24 broadcast", "192.168.1.255"},
+		{"192.168.1.0/24 hosts", "254"},
+		{"10.0.0.0/31 hosts", "2"},
+		{"2001:db8::/120 hosts", "256"},
+		{"2001:db8::/120 broadcast", "2001:db8::ff"},
+		{"10.0.0.0/8 10.1.2.3 contains", "1"},
+		{"10.0.0.0/8 11.0.0.1 contains", "0"},
+		{"10.0.0.0/8 10.1.0.0/16 contains", "1"},
+		{"10.0.0.0/24 26 subnet", "10.0.0.0/26 10.0.0.64/26 10.0.0.128/26 10.0.0.192/26"},
+		{"10.0.0.0/8 20 subnet depth n= clr n", "4096"},
+		{"192.168.1.0/24 supernet", "192.168.0.0/23"},
+		{"10.0.0.1 ip>int hex", "0xa000001"},
+		{"0xa000001 int>ip", "10.0.0.1"},
+		{"0x100000000 int>ip", "::1:0:0"},
+		{"::ffff:1.2.3.4 ip>int hex", "0xffff01020304"},
+	})
+}
+
+func TestIPErrors(t *testing.T) {
+	for _, line := range []string{
+		"10.0.0.0/33",
+		"::/129",
+		"1.2.3.4/-1",
+		"10.0.0.0/24 23 subnet",
+		"10.0.0.0/8 21 subnet",
+		"10.0.0.0/24 33 subnet",
+		"0.0.0.0/0 supernet",
+		"-1 int>ip",
+		"1 netmask",
+	} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package rpn
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// jsonState is the document written by WriteJSON, its layout is documented
+// in the README and only grows new fields.
+type jsonState struct {
+	Base  int        `json:"base"`
+	Stack []jsonItem `json:"stack"`
+	Vars  []jsonVar  `json:"vars"`
+	Error string     `json:"error,omitempty"`
+}
+
+// jsonItem is an item as saved in sessions, along with its text in the
+// display mode.
+type jsonItem struct {
+	item
+	Display string `json:"display"`
+}
+
+type jsonVar struct {
+	Name string `json:"name"`
+	jsonItem
+}
+
+var bases = map[string]int{"dec": 10, "hex": 16, "bin": 2, "oct": 8}
+
+// WriteJSON writes the stack, the variables by name and err, if not nil, as
+// a JSON document. It is indented, or on a single line for NDJSON.
+func (in *Interpreter) WriteJSON(out io.Writer, err error, indent bool) error {
+	s := jsonState{
+		Base:  bases[in.Mode],
+		Stack: make([]jsonItem, 0, len(in.stack)),
+		Vars:  make([]jsonVar, 0, len(in.vars)),
+	}
+	for _, v := range in.stack {
+		s.Stack = append(s.Stack, in.jsonItem(v))
+	}
+	names := make([]string, 0, len(in.vars))
+	for k := range in.vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		s.Vars = append(s.Vars, jsonVar{Name: k, jsonItem: in.jsonItem(in.vars[k])})
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "\t")
+	}
+	return enc.Encode(s)
+}
+
+func (in *Interpreter) jsonItem(v Var) jsonItem {
+	it := jsonItem{item: saveItem(v)}
+	if v.Type == Code && v.V != "" {
+		it.Display = formatCode(v.Code)
+	} else {
+		it.Display = in.Format(v)
+	}
+	return it
+}
@@ -0,0 +1,34 @@
+package rpn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	in := evalLines(t, "hex 255 1/3 x= \"0001\" hex>")
+	var b bytes.Buffer
+	if err := in.WriteJSON(&b, errors.New("oops"), false); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"base":16,"stack":[{"type":"Number","value":"255","prec":64,"display":"0xff"},` +
+		`{"type":"String","value":"\u0000\u0001","display":"<00 01>"}],` +
+		`"vars":[{"name":"x","type":"Rational","value":"1/3","display":"0x0"}],"error":"oops"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	b.Reset()
+	if err := in.WriteJSON(&b, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	var s jsonState
+	if err := json.Unmarshal(b.Bytes(), &s); err != nil || len(s.Stack) != 2 || s.Error != "" {
+		t.Errorf("the indented document reads back as %+v, %v", s, err)
+	}
+	if !strings.Contains(b.String(), "\n\t\"stack\"") {
+		t.Errorf("the document isn't indented: %s", b.String())
+	}
+}
@@ -0,0 +1,219 @@
+package rpn
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Kinds of remainders of a / b, which differ for negative operands: trunc has
+// the sign of a, like Go's %, floor has the sign of b, like Python's %, and
+// euclid is never negative.
+const (
+	truncMod = iota
+	floorMod
+	euclidMod
+)
+
+// remainder returns an op for the remainder of a / b of a kind, exact for
+// Rationals and rounded once for Numbers.
+func remainder(kind int) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := toRat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toRat(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		q := new(big.Rat).Quo(a, b)
+		r := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+		r.Sub(a, r.Mul(r, b))
+		switch {
+		case kind == floorMod && r.Sign()*b.Sign() < 0:
+			r.Add(r, b)
+		case kind == euclidMod && r.Sign() < 0:
+			r.Add(r, new(big.Rat).Abs(b))
+		}
+		if args[0].Type == Rational && args[1].Type == Rational {
+			return rational(r), nil
+		}
+		return number(in.newFloat().SetRat(r)), nil
+	}}
+}
+
+// toWhole returns the integer of a Number or Rational, which unlike with
+// toInt mustn't have a fractional part, nor have been rounded, like 2**64 + 1
+// at 64 bits.
+func toWhole(v Var) (*big.Int, error) {
+	if v.Type == Number && v.F.Acc() != big.Exact {
+		return nil, ErrDomain{Value: v.F.String(), Rounded: true}
+	}
+	r, err := toRat(v)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		if v.Type == Number {
+			return nil, ErrDomain{Value: v.F.String()}
+		}
+		return nil, ErrDomain{Value: r.RatString()}
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// whole wraps words on n integers.
+func whole(n int, f func(in *Interpreter, x []*big.Int) ([]Var, error)) op {
+	return op{n, func(in *Interpreter, args []Var) ([]Var, error) {
+		x := make([]*big.Int, len(args))
+		for i, v := range args {
+			var err error
+			if x[i], err = toWhole(v); err != nil {
+				return nil, err
+			}
+		}
+		return f(in, x)
+	}}
+}
+
+// primeRounds is the number of Miller-Rabin rounds of ProbablyPrime, which
+// also runs a Baillie-PSW test.
+const primeRounds = 20
+
+// nextPrime returns the smallest prime greater than n.
+func nextPrime(n *big.Int) *big.Int {
+	two := big.NewInt(2)
+	if n.Cmp(two) < 0 {
+		return two
+	}
+	p := new(big.Int).Add(n, big.NewInt(1))
+	if p.Bit(0) == 0 {
+		p.Add(p, big.NewInt(1))
+	}
+	for !p.ProbablyPrime(primeRounds) {
+		p.Add(p, two)
+	}
+	return p
+}
+
+// maxFactorBits bounds the integers factor accepts, and maxRhoSteps the
+// steps of rho, enough for the factors below about 2**36, so that they fail
+// in about a second rather than run for hours on large semiprimes.
+const (
+	maxFactorBits = 512
+	maxRhoSteps   = 1 << 18
+)
+
+// factor returns the prime factors of n > 0, with repeats, in ascending
+// order. Small factors are found by trial division and the others by
+// Pollard's rho.
+func factor(n *big.Int) ([]*big.Int, error) {
+	if n.BitLen() > maxFactorBits {
+		return nil, ErrDomain{Value: n.String()}
+	}
+	orig := n
+	n = new(big.Int).Set(n)
+	var fs []*big.Int
+	d, m := new(big.Int), new(big.Int)
+	for i := int64(2); i < 1000 && n.Cmp(big.NewInt(i*i)) >= 0; i++ {
+		d.SetInt64(i)
+		for m.Mod(n, d).Sign() == 0 {
+			fs = append(fs, big.NewInt(i))
+			n.Quo(n, d)
+		}
+	}
+	var split func(n *big.Int) bool
+	split = func(n *big.Int) bool {
+		if n.ProbablyPrime(primeRounds) {
+			fs = append(fs, n)
+			return true
+		}
+		d := rho(n)
+		return d != nil && split(d) && split(new(big.Int).Quo(n, d))
+	}
+	if n.Cmp(big.NewInt(1)) > 0 && !split(n) {
+		return nil, ErrDomain{Value: orig.String()}
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Cmp(fs[j]) < 0 })
+	return fs, nil
+}
+
+// rho returns a factor of the composite n, other than 1 and n, by Pollard's
+// rho with Floyd's cycle detection on x² + c, trying c = 1, 2, ... until one
+// is found, or nil after maxRhoSteps.
+func rho(n *big.Int) *big.Int {
+	one := big.NewInt(1)
+	steps := 0
+	for c := int64(1); steps < maxRhoSteps; c++ {
+		c := big.NewInt(c)
+		next := func(x *big.Int) {
+			x.Mul(x, x).Add(x, c).Mod(x, n)
+		}
+		x, y, d := big.NewInt(2), big.NewInt(2), big.NewInt(1)
+		for d.Cmp(one) == 0 && steps < maxRhoSteps {
+			steps++
+			next(x)
+			next(y)
+			next(y)
+			d.GCD(nil, nil, d.Sub(x, y).Abs(d), n)
+		}
+		if d.Cmp(one) != 0 && d.Cmp(n) != 0 {
+			return d
+		}
+	}
+	return nil
+}
+
+// totient returns Euler's totient of n > 0, from its prime factors.
+func totient(n *big.Int) (*big.Int, error) {
+	phi := new(big.Int).Set(n)
+	fs, err := factor(n)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range fs {
+		if i > 0 && p.Cmp(fs[i-1]) == 0 {
+			continue
+		}
+		// phi *= 1 - 1/p
+		phi.Quo(phi, p)
+		phi.Mul(phi, new(big.Int).Sub(p, big.NewInt(1)))
+	}
+	return phi, nil
+}
+
+// fib returns the n'th Fibonacci number, by fast doubling:
+// F(2k) = F(k)(2F(k+1) - F(k)) and F(2k+1) = F(k)² + F(k+1)².
+func fib(n *big.Int) *big.Int {
+	a, b := big.NewInt(0), big.NewInt(1) // F(k), F(k+1)
+	for i := n.BitLen() - 1; i >= 0; i-- {
+		c := new(big.Int).Lsh(b, 1)
+		c.Mul(c.Sub(c, a), a)
+		d := new(big.Int).Mul(a, a)
+		d.Add(d, new(big.Int).Mul(b, b))
+		a, b = c, d
+		if n.Bit(i) == 1 {
+			a, b = b, a.Add(a, b)
+		}
+	}
+	return a
+}
+
+// modInverse returns the inverse of a modulo |m|, in [0, |m|).
+func modInverse(a, m *big.Int) (*big.Int, error) {
+	if m.Sign() == 0 {
+		return nil, ErrDivisionByZero{}
+	}
+	m = new(big.Int).Abs(m)
+	if m.Cmp(big.NewInt(1)) == 0 {
+		return new(big.Int), nil
+	}
+	z := new(big.Int).ModInverse(new(big.Int).Mod(a, m), m)
+	if z == nil || z.Sign() == 0 {
+		return nil, ErrDomain{Value: a.String()}
+	}
+	return z, nil
+}
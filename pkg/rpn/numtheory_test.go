@@ -0,0 +1,96 @@
+package rpn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNumberTheory(t *testing.T) {
+	testEval(t, []evalTest{
+		{"12 18 gcd", "6"},
+		{"4 6 lcm", "12"},
+		{"4 13 497 modpow", "445"},
+		{"3 11 modinv", "4"},
+		{"97 isprime", "1"},
+		{"91 isprime", "0"},
+		{"rat 2 127 pow 1 - isprime", "1"},
+		{"rat 1 127 << 1 - isprime", "1"},
+		{"128 prec 170141183460469231731687303715884105727 isprime", "1"},
+		{"100 nextprime", "101"},
+		{"360 factor", "2 2 2 3 3 5 6"},
+		{"rat 2 64 pow 1 + factor", "274177 67280421310721 2"},
+		{"rat 2305843027467304993 factor", "1073741827 2147483659 2"},
+		{"36 totient", "12"},
+		{"5 2 binom", "10"},
+		{"10 fib", "55"},
+		{"1000 fib 10 % 1 fib", "5 1"},
+		{"1000 998 binom", "499500"},
+		{"17 isqrt", "4"},
+		{"1024 ilog2", "10"},
+		{"5 fact", "120"},
+		{"-7 3 %", "-1"},
+		{"-7 3 mod", "2"},
+		{"-7 -3 emod", "2"},
+		{"rat 7/2 1 %", "1/2"},
+	})
+}
+
+func TestExactPow(t *testing.T) {
+	testEval(t, []evalTest{
+		{"3 41 pow hex", "0x1fa2a1cf67b5fb863"},
+		{"rat 3 41 pow", "36472996377170786403"},
+		{"2 -2 pow", "0.25"},
+		{"1/3 3 pow", "1/27"},
+		{"2/3 -2 pow", "9/4"},
+		{"-2 3 **", "-8"},
+		{"2 0.5 pow", "1.4142135623730950488"},
+		{"1.5 2 pow", "2.25"},
+		{"1 1e18 pow", "1"},
+	})
+	var e ErrDivisionByZero
+	if err := newTest().Eval("0 -1 pow"); !errors.As(err, &e) {
+		t.Errorf("0 -1 pow: got %v", err)
+	}
+}
+
+func TestRoundedIntegers(t *testing.T) {
+	for _, line := range []string{
+		"2 127 pow 1 - isprime",
+		"1 127 << 1 - isprime",
+		"170141183460469231731687303715884105727 isprime",
+		"2 64 pow 1 + factor",
+		"1.5 fact",
+		"-1 fact",
+		"0 0 modinv",
+		"4 6 modinv",
+		"0 factor",
+	} {
+		var e ErrDomain
+		var z ErrDivisionByZero
+		if err := newTest().Eval(line); !errors.As(err, &e) && !errors.As(err, &z) {
+			t.Errorf("%q: got %v, want a domain error", line, err)
+		}
+	}
+}
+
+func TestNumberTheoryBounds(t *testing.T) {
+	for _, line := range []string{
+		"1000000000000 fib",
+		"100000000 fact",
+		"1000000000 500000000 binom",
+		"rat 2535301200456606295881202795651 factor",
+		"rat 2535301200456606295881202795651 totient",
+		"rat 2 600 pow factor",
+	} {
+		var e ErrDomain
+		if err := newTest().Eval(line); !errors.As(err, &e) || e.Rounded {
+			t.Errorf("%q: got %v, want a domain error", line, err)
+		}
+	}
+	var e ErrDomain
+	err := newTest().Eval("12345678901234567890123 fact")
+	if !errors.As(err, &e) || !e.Rounded || !strings.Contains(err.Error(), "was rounded") {
+		t.Errorf("a rounded operand gave %v", err)
+	}
+}
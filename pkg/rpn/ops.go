@@ -0,0 +1,1144 @@
+package rpn
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// op is a word that takes a fixed number of items from the top of the
+// stack and pushes its results in their place.
+type op struct {
+	arity int
+	fn    func(in *Interpreter, args []Var) ([]Var, error)
+}
+
+// ops holds the words that only look at the top of the stack, the ones that
+// need the whole stack or the rest of the line are handled by eval.
+var ops = map[string]op{
+	// Arithmetic Operators
+
+	"+": complex2(cadd, exact2(func(z, a, b *big.Rat) (*big.Rat, error) { return z.Add(a, b), nil },
+		float2(func(z, a, b *big.Float) (*big.Float, error) { return z.Add(a, b), nil }))),
+	"-": complex2(csub, exact2(func(z, a, b *big.Rat) (*big.Rat, error) { return z.Sub(a, b), nil },
+		float2(func(z, a, b *big.Float) (*big.Float, error) { return z.Sub(a, b), nil }))),
+	"*": complex2(cmul, exact2(func(z, a, b *big.Rat) (*big.Rat, error) { return z.Mul(a, b), nil },
+		float2(func(z, a, b *big.Float) (*big.Float, error) { return z.Mul(a, b), nil }))),
+	"/": complex2(cdiv, exact2(func(z, a, b *big.Rat) (*big.Rat, error) {
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		return z.Quo(a, b), nil
+	}, float2(func(z, a, b *big.Float) (*big.Float, error) {
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		return z.Quo(a, b), nil
+	}))),
+	"!": logic1(func(a bool) bool { return !a }),
+	"%": remainder(truncMod),
+	"++": exact1(func(z, a *big.Rat) (*big.Rat, error) { return z.Add(a, big.NewRat(1, 1)), nil },
+		float1(func(z, a *big.Float) (*big.Float, error) { return z.Add(a, big.NewFloat(1)), nil })),
+	"--": exact1(func(z, a *big.Rat) (*big.Rat, error) { return z.Sub(a, big.NewRat(1, 1)), nil },
+		float1(func(z, a *big.Float) (*big.Float, error) { return z.Sub(a, big.NewFloat(1)), nil })),
+
+	// Bitwise Operators
+
+	"&": integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).And(a, b), nil }),
+	"|": integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Or(a, b), nil }),
+	"^": integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Xor(a, b), nil }),
+	"~": integer1(func(a *big.Int) (*big.Int, error) { return new(big.Int).Not(a), nil }),
+	"<<": integer2(func(a, b *big.Int) (*big.Int, error) {
+		if !b.IsUint64() {
+			return nil, ErrDomain{Value: b.String()}
+		}
+		return new(big.Int).Lsh(a, uint(b.Uint64())), nil
+	}),
+	">>": integer2(func(a, b *big.Int) (*big.Int, error) {
+		if !b.IsUint64() {
+			return nil, ErrDomain{Value: b.String()}
+		}
+		return new(big.Int).Rsh(a, uint(b.Uint64())), nil
+	}),
+
+	// Boolean Operators
+
+	"&&": logic2(func(a, b bool) bool { return a && b }),
+	"||": logic2(func(a, b bool) bool { return a || b }),
+	"^^": logic2(func(a, b bool) bool { return a != b }),
+
+	// Comparison Operators
+
+	"!=": compare(func(c int) bool { return c != 0 }),
+	"<":  compare(func(c int) bool { return c < 0 }),
+	"<=": compare(func(c int) bool { return c <= 0 }),
+	"==": compare(func(c int) bool { return c == 0 }),
+	">":  compare(func(c int) bool { return c > 0 }),
+	">=": compare(func(c int) bool { return c >= 0 }),
+
+	// Trigonometric Functions
+
+	"acos": complex1(cacos, bigFunc(bigAcos)),
+	"asin": complex1(casin, bigFunc(bigAsin)),
+	"atan": complex1(catan, bigFunc(bigAtan)),
+	"cos":  complex1(ccos, bigFunc(bigCos)),
+	"cosh": complex1(ccosh, bigFunc(bigCosh)),
+	"sin":  complex1(csin, bigFunc(bigSin)),
+	"sinh": complex1(csinh, bigFunc(bigSinh)),
+	"tan":  complex1(ctan, bigFunc(bigTan)),
+	"tanh": complex1(ctanh, bigFunc(bigTanh)),
+
+	// Constants
+
+	"e":    constant(bigE),
+	"pi":   constant(bigPi),
+	"rand": constant(func(prec uint) *big.Float { return big.NewFloat(rand.Float64()) }), // [0.0,1.0)
+
+	// Precision
+
+	"prec": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Set the mantissa bits, e.g. '256 prec'
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() <= 0 || n.Cmp(big.NewInt(big.MaxPrec)) > 0 {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		in.Prec = uint(n.Uint64())
+		return nil, nil
+	}},
+	"rmode": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Set the rounding mode, 0 to 5 in big.RoundingMode's order
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() < 0 || n.Cmp(big.NewInt(int64(big.ToPositiveInf))) > 0 {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		in.RoundingMode = big.RoundingMode(n.Int64())
+		return nil, nil
+	}},
+
+	// IEEE-754 Floats
+
+	"f16>bits":   f16.toBits(),
+	"bf16>bits":  bf16.toBits(),
+	"f32>bits":   f32.toBits(),
+	"f64>bits":   f64.toBits(),
+	"bits>f16":   f16.fromBits(),
+	"bits>bf16":  bf16.fromBits(),
+	"bits>f32":   f32.fromBits(),
+	"bits>f64":   f64.fromBits(),
+	">f16":       f16.rounding(),
+	">bf16":      bf16.rounding(),
+	">f32":       f32.rounding(),
+	">f64":       f64.rounding(),
+	"f16>parts":  f16.toParts(),
+	"bf16>parts": bf16.toParts(),
+	"f32>parts":  f32.toParts(),
+	"f64>parts":  f64.toParts(),
+	"f16>str":    f16.toStr(),
+	"bf16>str":   bf16.toStr(),
+	"f32>str":    f32.toStr(),
+	"f64>str":    f64.toStr(),
+
+	// Word Size
+
+	"ws": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Set the word size in bits, 0 for unbounded integers, e.g. '16 ws'
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() < 0 || n.Cmp(big.NewInt(maxWordSize)) > 0 {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		in.WordSize = uint(n.Uint64())
+		return nil, nil
+	}},
+	"signed": {0, func(in *Interpreter, args []Var) ([]Var, error) { // Wrap to two's complement signed integers
+		in.Signed = true
+		return nil, nil
+	}},
+	"unsigned": {0, func(in *Interpreter, args []Var) ([]Var, error) { // Wrap to unsigned integers (default)
+		in.Signed = false
+		return nil, nil
+	}},
+
+	// Numeric Utilities
+
+	"abs": modulus(exact1(func(z, a *big.Rat) (*big.Rat, error) { return z.Abs(a), nil },
+		float1(func(z, a *big.Float) (*big.Float, error) { return z.Abs(a), nil }))),
+	"max": pick2(func(c int) bool { return c >= 0 }),
+	"min": pick2(func(c int) bool { return c <= 0 }),
+
+	// Rationals
+
+	"rat": {0, func(in *Interpreter, args []Var) ([]Var, error) { // Parse the numbers of the next lines as exact rationals
+		in.Rat = true
+		return nil, nil
+	}},
+	"float": {0, func(in *Interpreter, args []Var) ([]Var, error) { // Parse the numbers of the next lines as floats (default)
+		in.Rat = false
+		return nil, nil
+	}},
+	">rat": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Convert a number to a rational, exactly
+		r, err := toRat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return rational(r), nil
+	}},
+	">float": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Convert a number to a float
+		f, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(f)), nil
+	}},
+
+	// Complex Numbers
+
+	"cmplx": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Make a complex number, 'a b cmplx' is a+bi
+		re, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		im, err := in.toFloat(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return in.complex(bigComplex{re, im}), nil
+	}},
+	"re": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Real part
+		z, err := in.toComplex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(z.re)), nil
+	}},
+	"im": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Imaginary part
+		z, err := in.toComplex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(z.im)), nil
+	}},
+	"arg": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Argument, in (-pi, pi]
+		z, err := in.toComplex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := carg(z, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(r)), nil
+	}},
+	"conj": complex1(cconj, op{1, func(in *Interpreter, args []Var) ([]Var, error) { // Complex conjugate, a real is its own
+		if _, err := sign(args[0]); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}}),
+	"polar": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Convert to modulus and argument
+		z, err := in.toComplex(args[0])
+		if err != nil {
+			return nil, err
+		}
+		theta, err := carg(z, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: Number, F: in.round(cabs(z, in.Prec))}, {Type: Number, F: in.round(theta)}}, nil
+	}},
+	"rect": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Convert a modulus and argument to a complex number
+		r, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		theta, err := in.toFloat(args[1])
+		if err != nil {
+			return nil, err
+		}
+		z, err := cexp(bigComplex{new(big.Float), theta}, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return in.complex(bigComplex{mul(z.re, z.re, r), mul(z.im, z.im, r)}), nil
+	}},
+
+	// Linear Algebra, of the vectors and matrices
+
+	"dot": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Dot product of two vectors
+		a, err := in.toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toMatrix(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if !a.flat || !b.flat || len(a.rows[0]) != len(b.rows[0]) {
+			return nil, ErrShape{A: shape(args[0]), B: shape(args[1])}
+		}
+		z := new(big.Rat)
+		for i, x := range a.rows[0] {
+			z.Add(z, new(big.Rat).Mul(x, b.rows[0][i]))
+		}
+		return []Var{in.scalar(z, a.exact && b.exact)}, nil
+	}},
+	"cross": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Cross product of two vectors of 3 numbers
+		a, err := in.toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toMatrix(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if !a.flat || !b.flat || len(a.rows[0]) != 3 || len(b.rows[0]) != 3 {
+			return nil, ErrShape{A: shape(args[0]), B: shape(args[1])}
+		}
+		x, y := a.rows[0], b.rows[0]
+		c := make([]*big.Rat, 3)
+		for i := range c {
+			j, k := (i+1)%3, (i+2)%3
+			c[i] = new(big.Rat).Mul(x[j], y[k])
+			c[i].Sub(c[i], new(big.Rat).Mul(x[k], y[j]))
+		}
+		return []Var{in.fromRows([][]*big.Rat{c}, a.exact && b.exact, true)}, nil
+	}},
+	"transpose": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Transpose a matrix, a vector becomes a column
+		m, err := in.toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Var{in.fromRows(transpose(m.rows), m.exact, false)}, nil
+	}},
+	"det": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Determinant of a square matrix
+		m, err := in.square(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Var{in.scalar(eliminate(m.rows, make([][]*big.Rat, len(m.rows))), m.exact)}, nil
+	}},
+	"inv": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Inverse of a square matrix
+		m, err := in.square(args[0])
+		if err != nil {
+			return nil, err
+		}
+		x, err := in.solve(args[0], m.rows, identity(len(m.rows)))
+		if err != nil {
+			return nil, err
+		}
+		return []Var{in.fromRows(x, m.exact, false)}, nil
+	}},
+	"matmul": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Matrix product, a vector is a row on the left and a column on the right
+		a, err := in.toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toMatrix(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if b.flat {
+			b.rows = transpose(b.rows)
+		}
+		if len(a.rows[0]) != len(b.rows) {
+			return nil, ErrShape{A: shape(args[0]), B: shape(args[1])}
+		}
+		rows := make([][]*big.Rat, len(a.rows))
+		for i := range rows {
+			rows[i] = make([]*big.Rat, len(b.rows[0]))
+			for j := range rows[i] {
+				z := new(big.Rat)
+				for k := range b.rows {
+					z.Add(z, new(big.Rat).Mul(a.rows[i][k], b.rows[k][j]))
+				}
+				rows[i][j] = z
+			}
+		}
+		if b.flat {
+			rows = transpose(rows)
+		}
+		return []Var{in.fromRows(rows, a.exact && b.exact, a.flat || b.flat)}, nil
+	}},
+	"solve": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Solve A x = b for x, e.g. '[[2 1] [1 3]] [3 5] solve'
+		a, err := in.square(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toMatrix(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if b.flat {
+			b.rows = transpose(b.rows)
+		}
+		if len(b.rows) != len(a.rows) {
+			return nil, ErrShape{A: shape(args[0]), B: shape(args[1])}
+		}
+		x, err := in.solve(args[0], a.rows, b.rows)
+		if err != nil {
+			return nil, err
+		}
+		if b.flat {
+			x = transpose(x)
+		}
+		return []Var{in.fromRows(x, a.exact && b.exact, b.flat)}, nil
+	}},
+	"norm": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Euclidean norm of a vector, Frobenius norm of a matrix
+		m, err := in.toMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		z := new(big.Rat)
+		for _, row := range m.rows {
+			for _, x := range row {
+				z.Add(z, new(big.Rat).Mul(x, x))
+			}
+		}
+		f, err := bigSqrt(newFloat(in.Prec+guardBits).SetRat(z), in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(f)), nil
+	}},
+	"identity": {1, func(in *Interpreter, args []Var) ([]Var, error) { // n×n identity matrix, e.g. '3 identity'
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if n.Sign() <= 0 || n.Cmp(big.NewInt(1<<12)) > 0 {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		return []Var{in.fromRows(identity(int(n.Int64())), in.Rat, false)}, nil
+	}},
+
+	// Strings
+
+	"concat": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Concatenate two strings
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return str(a + b), nil
+	}},
+	"len": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Length of a string, in characters
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(big.NewInt(int64(utf8.RuneCountInString(a)))), nil
+	}},
+	"substr": {3, func(in *Interpreter, args []Var) ([]Var, error) { // Substring, e.g. '"hello" 1 3 substr' is "ell"
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		start, err := toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		n, err := toInt(args[2])
+		if err != nil {
+			return nil, err
+		}
+		r := []rune(a)
+		end := new(big.Int).Add(start, n)
+		if start.Sign() < 0 || n.Sign() < 0 || end.Cmp(big.NewInt(int64(len(r)))) > 0 {
+			return nil, ErrDomain{Value: fmt.Sprintf("%v+%v of %d characters", start, n, len(r))}
+		}
+		return str(string(r[start.Int64():end.Int64()])), nil
+	}},
+	"upper": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Upper case
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return str(strings.ToUpper(a)), nil
+	}},
+	"lower": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Lower case
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return str(strings.ToLower(a)), nil
+	}},
+	"split": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Split a string at a separator, pushing the parts and their count
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sep, err := toString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(a, sep)
+		res := make([]Var, 0, len(parts)+1)
+		for _, p := range parts {
+			res = append(res, str(p)...)
+		}
+		return append(res, in.integer(big.NewInt(int64(len(parts))))...), nil
+	}},
+	"find": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Index of a substring, in characters, or -1
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sub, err := toString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(a, sub)
+		if i > 0 {
+			i = utf8.RuneCountInString(a[:i])
+		}
+		return in.integer(big.NewInt(int64(i))), nil
+	}},
+	"replace": {3, func(in *Interpreter, args []Var) ([]Var, error) { // Replace every occurrence, e.g. '"a-b" "-" "+" replace'
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		old, err := toString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		s, err := toString(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return str(strings.Replace(a, old, s, -1)), nil
+	}},
+	"str>num": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Parse a string as a number
+		a, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		v, ok := in.parseNumber(strings.TrimSpace(a))
+		if !ok {
+			return nil, ErrDomain{Value: strconv.Quote(a)}
+		}
+		return []Var{v}, nil
+	}},
+	"num>str": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Format a number in the current display mode
+		if _, err := in.toComplex(args[0]); err != nil {
+			return nil, err
+		}
+		return str(in.text(args[0])), nil
+	}},
+	"print": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Print the top item, strings without quotes
+		fmt.Fprintln(in.Tee(in.Out), in.text(args[0]))
+		return nil, nil
+	}},
+
+	// Mathematic Functions
+
+	"pow": complex2(cpow, exactPow(bigFunc2(bigPow))),
+	"**":  complex2(cpow, exactPow(bigFunc2(bigPow))),
+	"exp": complex1(cexp, bigFunc(bigExp)),
+	"fact": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) {
+		// n! has fewer than n*bits(n) bits
+		n := x[0]
+		if n.Sign() < 0 || n.Cmp(big.NewInt(maxPowBits)) > 0 || n.Int64()*int64(n.BitLen()) > maxPowBits {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		return in.integer(new(big.Int).MulRange(1, n.Int64())), nil
+	}),
+	"sqrt": complex1(csqrt, bigFunc(bigSqrt)),
+	"ln":   complex1(cln, bigFunc(bigLn)),
+	"log":  bigFunc(bigLog10),
+
+	// Number Theory, of integers
+
+	"mod":  remainder(floorMod),  // Floored modulus, with the sign of the divisor, e.g. '-7 3 mod' is 2
+	"emod": remainder(euclidMod), // Euclidean modulus, never negative, e.g. '-7 -3 emod' is 2
+	"gcd": whole(2, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Greatest common divisor
+		return in.integer(new(big.Int).GCD(nil, nil, x[0], x[1])), nil
+	}),
+	"lcm": whole(2, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Least common multiple
+		if x[0].Sign() == 0 || x[1].Sign() == 0 {
+			return in.integer(new(big.Int)), nil
+		}
+		z := new(big.Int).GCD(nil, nil, x[0], x[1])
+		z.Mul(new(big.Int).Quo(x[0], z), x[1])
+		return in.integer(z.Abs(z)), nil
+	}),
+	"modpow": whole(3, func(in *Interpreter, x []*big.Int) ([]Var, error) { // a**b mod m, e.g. '4 13 497 modpow'
+		a, b := x[0], x[1]
+		if b.Sign() < 0 {
+			inv, err := modInverse(a, x[2])
+			if err != nil {
+				return nil, err
+			}
+			a, b = inv, new(big.Int).Neg(b)
+		}
+		if x[2].Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		m := new(big.Int).Abs(x[2])
+		return in.integer(new(big.Int).Exp(new(big.Int).Mod(a, m), b, m)), nil
+	}),
+	"modinv": whole(2, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Inverse of a modulo m, e.g. '3 11 modinv'
+		z, err := modInverse(x[0], x[1])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(z), nil
+	}),
+	"isprime": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Whether an integer is prime, certainly below 2**64
+		return in.boolean(x[0].Sign() > 0 && x[0].ProbablyPrime(primeRounds)), nil
+	}),
+	"nextprime": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Smallest prime greater than an integer
+		return in.integer(nextPrime(x[0])), nil
+	}),
+	"factor": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Push the prime factors in ascending order and their count
+		if x[0].Sign() <= 0 {
+			return nil, ErrDomain{Value: x[0].String()}
+		}
+		fs, err := factor(x[0])
+		if err != nil {
+			return nil, err
+		}
+		var res []Var
+		for _, p := range fs {
+			res = append(res, in.integer(p)...)
+		}
+		return append(res, in.integer(big.NewInt(int64(len(fs))))...), nil
+	}),
+	"totient": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Euler's totient, the count of the integers up to n coprime to it
+		if x[0].Sign() <= 0 {
+			return nil, ErrDomain{Value: x[0].String()}
+		}
+		phi, err := totient(x[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(phi), nil
+	}),
+	"binom": whole(2, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Binomial coefficient, n choose k, e.g. '5 2 binom'
+		n, k := x[0], x[1]
+		if n.Sign() < 0 || !n.IsInt64() {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		if k.Sign() < 0 || k.Cmp(n) > 0 {
+			return in.integer(new(big.Int)), nil
+		}
+		// the products of the smaller of k and n - k factors have fewer
+		// than k*bits(n) bits
+		if nk := new(big.Int).Sub(n, k); nk.Cmp(k) < 0 {
+			k = nk
+		}
+		if new(big.Int).Mul(k, big.NewInt(int64(n.BitLen()))).Cmp(big.NewInt(maxPowBits)) > 0 {
+			return nil, ErrDomain{Value: n.String()}
+		}
+		return in.integer(new(big.Int).Binomial(n.Int64(), k.Int64())), nil
+	}),
+	"fib": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // n'th Fibonacci number, e.g. '10 fib' is 55
+		// F(n) has about 0.69*n bits
+		if x[0].Sign() < 0 || x[0].Cmp(big.NewInt(maxPowBits)) > 0 {
+			return nil, ErrDomain{Value: x[0].String()}
+		}
+		return in.integer(fib(x[0])), nil
+	}),
+	"isqrt": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Integer square root, rounded down
+		if x[0].Sign() < 0 {
+			return nil, ErrDomain{Value: x[0].String()}
+		}
+		return in.integer(new(big.Int).Sqrt(x[0])), nil
+	}),
+	"ilog2": whole(1, func(in *Interpreter, x []*big.Int) ([]Var, error) { // Integer base 2 logarithm, rounded down
+		if x[0].Sign() <= 0 {
+			return nil, ErrDomain{Value: x[0].String()}
+		}
+		return in.integer(big.NewInt(int64(x[0].BitLen() - 1))), nil
+	}),
+
+	// Networking
+
+	"hnl": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Host to network long, the 4 bytes of an integer, big-endian
+		b, err := fixed(args[0], 4, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: String, B: b}}, nil
+	}},
+	"hns": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Host to network short, the 2 bytes of an integer, big-endian
+		b, err := fixed(args[0], 2, false)
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: String, B: b}}, nil
+	}},
+	"nhl": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Network to host long, the integer of 4 big-endian bytes
+		b, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 4 {
+			return nil, ErrDomain{Value: formatBytes([]byte(b))}
+		}
+		return in.integer(unfixed([]byte(b), false)), nil
+	}},
+	"nhs": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Network to host short, the integer of 2 big-endian bytes
+		b, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 2 {
+			return nil, ErrDomain{Value: formatBytes([]byte(b))}
+		}
+		return in.integer(unfixed([]byte(b), false)), nil
+	}},
+	"netmask": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Netmask of a network, e.g. '192.168.1.0/24 netmask'
+		n, err := ipNet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: IP, B: []byte(n.Mask), Prefix: -1}}, nil
+	}},
+	"network": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Network of an address, its host bits cleared, e.g. '192.168.1.77/24 network'
+		n, err := ipNet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		ones, _ := n.Mask.Size()
+		return []Var{{Type: IP, B: n.IP, Prefix: ones}}, nil
+	}},
+	"broadcast": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Broadcast address, or last address in IPv6, of a network
+		n, err := ipNet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Var{{Type: IP, B: lastIP(n), Prefix: -1}}, nil
+	}},
+	"hosts": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Number of host addresses of a network, without the network and broadcast addresses in IPv4
+		ip, prefix, err := toIP(args[0])
+		if err != nil {
+			return nil, err
+		}
+		bits := 8*len(ip) - prefix
+		n := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if len(ip) == net.IPv4len && bits > 1 {
+			n.Sub(n, big.NewInt(2))
+		}
+		return in.integer(n), nil
+	}},
+	"contains": {2, func(in *Interpreter, args []Var) ([]Var, error) { // Whether a network contains an address or network, e.g. '10.0.0.0/8 10.1.2.3 contains'
+		n, err := ipNet(args[0])
+		if err != nil {
+			return nil, err
+		}
+		ip, prefix, err := toIP(args[1])
+		if err != nil {
+			return nil, err
+		}
+		ones, _ := n.Mask.Size()
+		return in.boolean(len(ip) == len(n.IP) && prefix >= ones && n.Contains(ip)), nil
+	}},
+	"supernet": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Network one bit shorter, e.g. '192.168.1.0/24 supernet' gives 192.168.0.0/23
+		ip, prefix, err := toIP(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if prefix == 0 {
+			return nil, ErrDomain{Value: formatIP(args[0])}
+		}
+		n, _ := ipNet(Var{Type: IP, B: ip, Prefix: prefix - 1})
+		return []Var{{Type: IP, B: n.IP, Prefix: prefix - 1}}, nil
+	}},
+	"ip>int": {1, func(in *Interpreter, args []Var) ([]Var, error) { // Integer of an address
+		ip, _, err := toIP(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(new(big.Int).SetBytes(ip)), nil
+	}},
+	"int>ip": {1, func(in *Interpreter, args []Var) ([]Var, error) { // IPv4 address of an integer, or IPv6 past 32 bits
+		i, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if i.Sign() < 0 || i.BitLen() > 128 {
+			return nil, ErrDomain{Value: i.String()}
+		}
+		if i.BitLen() > 32 {
+			return []Var{address(i, net.IPv6len)}, nil
+		}
+		return []Var{address(i, net.IPv4len)}, nil
+	}},
+	"bswap16": bswap(2), // Swap the 2 bytes of an integer
+	"bswap32": bswap(4), // Swap the 4 bytes of an integer
+	"bswap64": bswap(8), // Swap the 8 bytes of an integer
+	"htons":   hton(2),  // Host to network order of a 16 bit integer
+	"htonl":   hton(4),  // Host to network order of a 32 bit integer
+	"htonll":  hton(8),  // Host to network order of a 64 bit integer
+	"ntohs":   hton(2),  // Network to host order of a 16 bit integer
+	"ntohl":   hton(4),  // Network to host order of a 32 bit integer
+	"ntohll":  hton(8),  // Network to host order of a 64 bit integer
+
+	// Checksums and Encodings, of the bytes of strings
+
+	"crc32":    checksum(crc32Sum),                         // CRC-32 (IEEE), e.g. '"123456789" crc32 hex'
+	"crc16":    checksum(crc16),                            // CRC-16/ARC
+	"adler32":  checksum(adler32Sum),                       // Adler-32
+	"fletcher": checksum(fletcher16),                       // Fletcher-16
+	"inetsum":  checksum(inetsum),                          // Internet checksum (RFC 1071)
+	"fnv":      checksum(fnv32),                            // 32 bit FNV-1a hash
+	"fnv64":    checksum(fnv64),                            // 64 bit FNV-1a hash
+	"md5":      digest(md5Sum),                             // MD5 digest, as bytes
+	"sha1":     digest(sha1Sum),                            // SHA-1 digest, as bytes
+	"sha256":   digest(sha256Sum),                          // SHA-256 digest, as bytes
+	">hex":     encoder(hex.EncodeToString),                // Encode bytes as hex text
+	"hex>":     decoder(hex.DecodeString),                  // Decode hex text to bytes
+	">base64":  encoder(base64.StdEncoding.EncodeToString), // Encode bytes as base64 text
+	"base64>":  decoder(base64.StdEncoding.DecodeString),   // Decode base64 text to bytes
+	">base32":  encoder(base32.StdEncoding.EncodeToString), // Encode bytes as base32 text
+	"base32>":  decoder(base32.StdEncoding.DecodeString),   // Decode base32 text to bytes
+
+	// Stack Manipulation
+
+	"drop": {1, func(in *Interpreter, args []Var) ([]Var, error) { return nil, nil }},
+	"dup":  {1, func(in *Interpreter, args []Var) ([]Var, error) { return []Var{args[0], args[0]}, nil }},
+	"swap": {2, func(in *Interpreter, args []Var) ([]Var, error) { return []Var{args[1], args[0]}, nil }},
+}
+
+func number(f *big.Float) []Var {
+	return []Var{{Type: Number, F: f}}
+}
+
+func rational(r *big.Rat) []Var {
+	return []Var{{Type: Rational, R: r}}
+}
+
+// integer returns an exact integer result, as a Rational in rat mode.
+func (in *Interpreter) integer(i *big.Int) []Var {
+	if in.Rat {
+		return rational(new(big.Rat).SetInt(i))
+	}
+	return number(new(big.Float).SetInt(i))
+}
+
+func (in *Interpreter) boolean(b bool) []Var {
+	if b {
+		return in.integer(big.NewInt(1))
+	}
+	return in.integer(big.NewInt(0))
+}
+
+// sign returns -1, 0 or 1 depending on the sign of a Number or Rational.
+func sign(v Var) (int, error) {
+	switch v.Type {
+	case Number:
+		return v.F.Sign(), nil
+	case Rational:
+		return v.R.Sign(), nil
+	}
+	return 0, ErrType{Want: Number, Got: v.Type}
+}
+
+// toFloat returns the value of a Number, or of a Rational rounded to the
+// interpreter's precision.
+func (in *Interpreter) toFloat(v Var) (*big.Float, error) {
+	switch v.Type {
+	case Number:
+		return v.F, nil
+	case Rational:
+		return in.newFloat().SetRat(v.R), nil
+	}
+	return nil, ErrType{Want: Number, Got: v.Type}
+}
+
+// toRat returns the exact value of a finite Number or Rational.
+func toRat(v Var) (*big.Rat, error) {
+	switch v.Type {
+	case Number:
+		if v.F.IsInf() {
+			return nil, ErrDomain{Value: v.F.String()}
+		}
+		r, _ := v.F.Rat(nil)
+		return r, nil
+	case Rational:
+		return v.R, nil
+	}
+	return nil, ErrType{Want: Number, Got: v.Type}
+}
+
+// toInt returns the integer part of a finite Number or Rational.
+func toInt(v Var) (*big.Int, error) {
+	switch v.Type {
+	case Number:
+		if v.F.IsInf() {
+			return nil, ErrDomain{Value: v.F.String()}
+		}
+		i, _ := v.F.Int(nil)
+		return i, nil
+	case Rational:
+		return new(big.Int).Quo(v.R.Num(), v.R.Denom()), nil
+	}
+	return nil, ErrType{Want: Number, Got: v.Type}
+}
+
+// cmpNumbers compares two Numbers or Rationals, exactly.
+func cmpNumbers(a, b Var) (int, error) {
+	if _, err := sign(a); err != nil {
+		return 0, err
+	}
+	if _, err := sign(b); err != nil {
+		return 0, err
+	}
+	switch {
+	case a.Type == Number && b.Type == Number:
+		return a.F.Cmp(b.F), nil
+	case a.Type == Number && a.F.IsInf():
+		return a.F.Sign(), nil
+	case b.Type == Number && b.F.IsInf():
+		return -b.F.Sign(), nil
+	}
+	x, _ := toRat(a)
+	y, _ := toRat(b)
+	return x.Cmp(y), nil
+}
+
+func compare(f func(c int) bool) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type == String && args[1].Type == String {
+			return in.boolean(f(bytes.Compare(args[0].B, args[1].B))), nil
+		}
+		c, err := cmpNumbers(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return in.boolean(f(c)), nil
+	}}
+}
+
+// pick2 keeps whichever operand f chooses, as it is.
+func pick2(f func(c int) bool) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		c, err := cmpNumbers(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		if f(c) {
+			return args[:1:1], nil
+		}
+		return args[1:2:2], nil
+	}}
+}
+
+func logic1(f func(a bool) bool) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := sign(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return in.boolean(f(a != 0)), nil
+	}}
+}
+
+func logic2(f func(a, b bool) bool) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := sign(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := sign(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return in.boolean(f(a != 0, b != 0)), nil
+	}}
+}
+
+// exact1 and exact2 compute with big.Rat when the operands are Rationals,
+// and fall back to o otherwise.
+func exact1(f func(z, a *big.Rat) (*big.Rat, error), o op) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type != Rational {
+			return o.fn(in, args)
+		}
+		r, err := f(new(big.Rat), args[0].R)
+		if err != nil {
+			return nil, err
+		}
+		return rational(r), nil
+	}}
+}
+
+func exact2(f func(z, a, b *big.Rat) (*big.Rat, error), o op) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		if args[0].Type != Rational || args[1].Type != Rational {
+			return o.fn(in, args)
+		}
+		r, err := f(new(big.Rat), args[0].R, args[1].R)
+		if err != nil {
+			return nil, err
+		}
+		return rational(r), nil
+	}}
+}
+
+// maxPowBits bounds the exact powers of exactPow, past it they are rounded,
+// and the results of fact, binom and fib, past it they fail.
+const maxPowBits = 1 << 20
+
+// exactPow wraps o, computing the powers of Rationals and exact integers to
+// integers exactly: Rationals give Rationals, and integers give integers
+// or, for negative exponents, their single rounding.
+func exactPow(o op) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, b := args[0], args[1]
+		if a.Type == Number && (!a.F.IsInt() || a.F.Acc() != big.Exact) || a.Type != Number && a.Type != Rational {
+			return o.fn(in, args)
+		}
+		n, err := toWhole(b)
+		if err != nil || !n.IsInt64() {
+			return o.fn(in, args)
+		}
+		x, _ := toRat(a)
+		abs := new(big.Int).Abs(n)
+		// the result has about bits*|n| bits
+		if bits := x.Num().BitLen() + x.Denom().BitLen() - 1; bits > 0 && abs.Cmp(big.NewInt(maxPowBits/int64(bits))) > 0 {
+			return o.fn(in, args)
+		}
+		num := new(big.Int).Exp(x.Num(), abs, nil)
+		den := new(big.Int).Exp(x.Denom(), abs, nil)
+		if n.Sign() < 0 {
+			if num.Sign() == 0 {
+				return nil, ErrDivisionByZero{}
+			}
+			num, den = den, num
+		}
+		r := new(big.Rat).SetFrac(num, den)
+		switch {
+		case a.Type == Rational:
+			return rational(r), nil
+		case r.IsInt():
+			return in.integer(r.Num()), nil
+		}
+		return number(in.newFloat().SetRat(r)), nil
+	}}
+}
+
+func constant(f func(prec uint) *big.Float) op {
+	return op{0, func(in *Interpreter, args []Var) ([]Var, error) {
+		return number(in.round(f(in.Prec))), nil
+	}}
+}
+
+// float1 and float2 wrap words on Numbers, z is set to the interpreter's
+// precision and rounding mode for the result.
+func float1(f func(z, a *big.Float) (*big.Float, error)) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(in.newFloat(), a)
+		if err != nil {
+			return nil, err
+		}
+		return number(r), nil
+	}}
+}
+
+func float2(f func(z, a, b *big.Float) (*big.Float, error)) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toFloat(args[1])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(in.newFloat(), a, b)
+		if err != nil {
+			return nil, err
+		}
+		return number(r), nil
+	}}
+}
+
+// integer1 and integer2 wrap words on the integer part of Numbers, their
+// results are exact.
+func integer1(f func(a *big.Int) (*big.Int, error)) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(a)
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(r), nil
+	}}
+}
+
+func integer2(f func(a, b *big.Int) (*big.Int, error)) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return in.integer(r), nil
+	}}
+}
+
+// bigFunc wraps one of the functions of bigmath.go, computing it to the
+// interpreter's precision.
+func bigFunc(f func(x *big.Float, prec uint) (*big.Float, error)) op {
+	return op{1, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(a, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(r)), nil
+	}}
+}
+
+func bigFunc2(f func(x, y *big.Float, prec uint) (*big.Float, error)) op {
+	return op{2, func(in *Interpreter, args []Var) ([]Var, error) {
+		a, err := in.toFloat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := in.toFloat(args[1])
+		if err != nil {
+			return nil, err
+		}
+		r, err := f(a, b, in.Prec)
+		if err != nil {
+			return nil, err
+		}
+		return number(in.round(r)), nil
+	}}
+}
@@ -0,0 +1,56 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPrec(t *testing.T) {
+	for _, line := range []string{"256 prec 0.1", "256 prec [ 0.1 ] call", "256 prec [0.1 1]"} {
+		in := evalLines(t, line)
+		v := in.stack[len(in.stack)-1]
+		if v.Type == Vector {
+			v = v.Elems[0]
+		}
+		want, _, _ := big.ParseFloat("0.1", 10, 256, big.ToNearestEven)
+		if v.F.Prec() != 256 || v.F.Cmp(want) != 0 {
+			t.Errorf("%q: got %s with %d bits, want 0.1 with 256 bits", line, v.F.Text('g', 80), v.F.Prec())
+		}
+	}
+	in := evalLines(t, "100 prec 2 sqrt")
+	if got := in.stack[0].F.Prec(); got != 100 {
+		t.Errorf("2 sqrt has %d bits at 100 prec", got)
+	}
+	for _, line := range []string{"0 prec", "-1 prec"} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
+
+func TestRmode(t *testing.T) {
+	down := evalLines(t, "8 prec 4 rmode 1 3 /").stack[0].F
+	up := evalLines(t, "8 prec 5 rmode 1 3 /").stack[0].F
+	if down.Cmp(up) >= 0 {
+		t.Errorf("rounding 1/3 down gave %v, not less than rounding it up, %v", down, up)
+	}
+	if m := evalLines(t, "3 rmode 0.1").stack[0].F.Mode(); m != big.AwayFromZero {
+		t.Errorf("a literal after 3 rmode is rounded %v", m)
+	}
+	if err := newTest().Eval("6 rmode"); err == nil {
+		t.Error("6 rmode didn't fail")
+	}
+}
+
+func TestRat(t *testing.T) {
+	testEval(t, []evalTest{
+		{"rat 1 3 /", "1/3"},
+		{"rat 1 3 / 0.1 +", "13/30"},
+		{"1/3 1/6 +", "1/2"},
+		{"rat 7 2 %", "1"},
+		{"rat 0.1 >float", "0.1"},
+		{"0.5 >rat", "1/2"},
+		{"rat float 1 4 /", "0.25"},
+		{"rat [ 1 3 / ] call", "1/3"},
+	})
+}
@@ -0,0 +1,178 @@
+package rpn
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// parseNumber parses decimal, hexadecimal (0x), octal (0o) and binary (0b)
+// numbers, rounding them to the interpreter's precision. Fractions like 1/3,
+// and every number in rat mode, are parsed as exact Rationals, and 3+4i as a
+// Complex.
+func (in *Interpreter) parseNumber(s string) (Var, bool) {
+	if v, ok := in.parseComplex(s); ok {
+		return v, true
+	}
+	if in.Rat || strings.Contains(s, "/") {
+		if r, ok := new(big.Rat).SetString(s); ok {
+			return Var{Type: Rational, R: r}, true
+		}
+	}
+	f, _, err := in.newFloat().Parse(s, 0)
+	return Var{Type: Number, F: f}, err == nil
+}
+
+// literal returns the number of a literal with the current precision,
+// rounding mode and number mode, and whether it changed, parsing it again
+// when they changed since the line was parsed, like after '256 prec' or
+// 'rat' on the same line.
+func (in *Interpreter) literal(v Var) (Var, bool) {
+	if v.Type == Vector {
+		var elems []Var
+		for i, e := range v.Elems {
+			l, changed := in.literal(e)
+			if changed && elems == nil {
+				elems = append(make([]Var, 0, len(v.Elems)), v.Elems[:i]...)
+			}
+			if elems != nil {
+				elems = append(elems, l)
+			}
+		}
+		if elems == nil {
+			return v, false
+		}
+		v.Elems = elems
+		return v, true
+	}
+	if v.lit == "" || in.parsedAs(v) {
+		return v, false
+	}
+	l, ok := in.parseNumber(v.lit)
+	if !ok {
+		return v, false
+	}
+	l.Pos, l.lit = v.Pos, v.lit
+	return l, true
+}
+
+// parsedAs tells if a number was parsed with the current precision,
+// rounding mode and number mode.
+func (in *Interpreter) parsedAs(v Var) bool {
+	switch v.Type {
+	case Number:
+		return !in.Rat && v.F.Prec() == in.Prec && v.F.Mode() == in.RoundingMode
+	case Complex:
+		return v.F.Prec() == in.Prec && v.F.Mode() == in.RoundingMode
+	case Rational:
+		return in.Rat || strings.Contains(v.lit, "/")
+	}
+	return true
+}
+
+// looksNumeric tells if a lexeme was meant to be a number, so it is reported
+// instead of being taken as a variable name.
+func looksNumeric(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return s != "" && (s[0] >= '0' && s[0] <= '9' || s[0] == '.')
+}
+
+// Parse turns lexemes into stack items.
+func (in *Interpreter) Parse(lex []string) ([]Var, error) {
+	stack := make([]Var, 0, len(lex))
+	// the items around the quotations being parsed, and where they start
+	var outer [][]Var
+	var starts []int
+	fileName := false // the lexeme is the file name of a word like save
+	for i := range lex {
+		if in.Debug {
+			fmt.Fprintf(in.Trace, "Parsing %s\n", lex[i])
+		}
+		switch {
+		case fileName && (lex[i] == "[" || lex[i] == "]"):
+			return nil, ErrSyntax{Op: lex[i-1], Pos: i - 1, Msg: "missing file name"}
+		case fileName:
+			v := Var{Type: String, B: []byte(lex[i])}
+			if strings.HasPrefix(lex[i], `"`) {
+				var ok bool
+				if v, ok = parseString(lex[i]); !ok {
+					return nil, ErrSyntax{Op: lex[i], Pos: i, Msg: "malformed string"}
+				}
+			}
+			v.Pos = i
+			stack = append(stack, v)
+		case lex[i] == "[":
+			outer = append(outer, stack)
+			starts = append(starts, i)
+			stack = nil
+		case lex[i] == "]":
+			if len(outer) == 0 {
+				return nil, ErrSyntax{Op: lex[i], Pos: i, Msg: "no matching ["}
+			}
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "quotation:", stack)
+			}
+			q, ok := vectorOf(stack)
+			if !ok {
+				q = Var{Type: Code, Code: stack}
+			}
+			q.Pos = starts[len(starts)-1]
+			stack = append(outer[len(outer)-1], q)
+			outer, starts = outer[:len(outer)-1], starts[:len(starts)-1]
+		case strings.HasPrefix(lex[i], `"`):
+			v, ok := parseString(lex[i])
+			if !ok {
+				return nil, ErrSyntax{Op: lex[i], Pos: i, Msg: "malformed string"}
+			}
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "String:", lex[i])
+			}
+			v.Pos = i
+			stack = append(stack, v)
+		case in.isKeyword(lex[i]):
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "keyword:", lex[i])
+			}
+			stack = append(stack, Var{Type: Code, V: lex[i], Pos: i})
+		case len(lex[i]) > 1 && strings.HasSuffix(lex[i], "="):
+			// if last char is =
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "variable assignment:", lex[i])
+			}
+			stack = append(stack, Var{Type: Assignment, V: lex[i][:len(lex[i])-1], Pos: i})
+		case looksIP(lex[i]):
+			v, ok := parseIP(lex[i])
+			if !ok {
+				return nil, ErrSyntax{Op: lex[i], Pos: i, Msg: "not an address"}
+			}
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "IP:", lex[i])
+			}
+			v.Pos = i
+			stack = append(stack, v)
+		case looksNumeric(lex[i]):
+			v, ok := in.parseNumber(lex[i])
+			if !ok {
+				return nil, ErrSyntax{Op: lex[i], Pos: i, Msg: "not a number"}
+			}
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "Number:", lex[i])
+			}
+			v.Pos, v.lit = i, lex[i]
+			stack = append(stack, v)
+		default:
+			if in.Debug {
+				fmt.Fprintln(in.Trace, "variable:", lex[i])
+			}
+			stack = append(stack, Var{Type: Variable, V: lex[i], Pos: i})
+		}
+		fileName = !fileName && defaultKeyWords[lex[i]] == "f"
+	}
+	if fileName {
+		return nil, ErrSyntax{Op: lex[len(lex)-1], Pos: len(lex) - 1, Msg: "missing file name"}
+	}
+	if len(starts) > 0 {
+		return nil, ErrSyntax{Op: "[", Pos: starts[len(starts)-1], Msg: "no matching ]"}
+	}
+	return stack, nil
+}
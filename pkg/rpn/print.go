@@ -0,0 +1,180 @@
+package rpn
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PrintStack writes the stack to out in the current display mode.
+func (in *Interpreter) PrintStack(out io.Writer) {
+	stack := in.stack
+	if len(stack) > 0 {
+		fmt.Fprint(out, "[ ")
+	}
+	for i := range stack {
+		if in.Vertical && isMatrix(stack[i]) {
+			in.printItem(out, in.formatRows(stack[i]))
+		} else if text, ok := in.formatItem(stack[i]); ok {
+			in.printItem(out, text)
+		}
+	}
+	if len(stack) > 0 {
+		fmt.Fprint(out, "\b ]")
+	}
+}
+
+// PrintVars writes the variables to out in the current display mode.
+func (in *Interpreter) PrintVars(out io.Writer) {
+	vars := in.vars
+	if len(vars) > 0 {
+		fmt.Fprintf(out, "map[ ")
+	}
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if text, ok := in.formatItem(vars[k]); ok {
+			in.printItem(out, k+":"+text)
+		}
+	}
+	if len(vars) > 0 {
+		fmt.Fprint(out, "\b ]")
+	}
+}
+
+func (in *Interpreter) printItem(out io.Writer, text string) {
+	if in.Vertical {
+		fmt.Fprintf(out, "%v\n", text)
+	} else {
+		fmt.Fprintf(out, "%v,", text)
+	}
+}
+
+// Format formats an item the way PrintStack does, in the current display
+// mode.
+func (in *Interpreter) Format(v Var) string {
+	text, _ := in.formatItem(v)
+	return text
+}
+
+// formatItem formats an item in the current display mode, it returns false
+// for items that aren't printed.
+func (in *Interpreter) formatItem(v Var) (string, bool) {
+	format := "%v"
+	switch in.Mode {
+	case "hex":
+		format = "%#x"
+	case "bin":
+		format = "%#b"
+	case "oct":
+		format = "%#o"
+	}
+	switch v.Type {
+	case Number, Rational:
+		if in.Mode != "dec" {
+			if tmp, err := toInt(v); err == nil {
+				if in.WordSize > 0 {
+					tmp = in.unsigned(tmp)
+				}
+				return fmt.Sprintf(format, tmp), true
+			}
+		}
+		if in.WordSize > 0 && (v.Type == Rational && v.R.IsInt() || v.Type == Number && v.F.IsInt()) {
+			// the value of the integer in a word, like the other bases show
+			x, _ := toInt(v)
+			return in.wrapInt(x).String(), true
+		}
+		if v.Type == Rational {
+			return v.R.RatString(), true
+		}
+		return fmt.Sprint(v.F), true
+	case Complex:
+		return formatComplex(v), true
+	case Vector:
+		return joinVector(v, in.Format), true
+	case IP:
+		return formatIP(v), true
+	case Code:
+		if v.V == "" {
+			return formatCode(v.Code), true
+		}
+	case Variable:
+		return v.V, true
+	case Assignment:
+		return v.V + "=", true
+	case String:
+		if isText(v.B) {
+			return strconv.Quote(string(v.B)), true
+		}
+		return formatBytes(v.B), true
+	}
+	return "", false
+}
+
+// formatComplex formats a Complex like 3+4i, in decimal whatever the mode.
+func formatComplex(v Var) string {
+	im := fmt.Sprint(v.I)
+	if !v.I.Signbit() {
+		im = "+" + im
+	}
+	return fmt.Sprint(v.F) + im + "i"
+}
+
+// isText tells text strings from the bytes of words like hnl, which are
+// printed in the display mode.
+func isText(b []byte) bool {
+	for _, r := range string(b) {
+		if r == utf8.RuneError || !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// source formats code so it parses back to the same items, in decimal
+// whatever the mode.
+func source(code []Var) string {
+	text := make([]string, 0, len(code))
+	for _, v := range code {
+		switch v.Type {
+		case Number:
+			text = append(text, fmt.Sprint(v.F))
+		case Rational:
+			text = append(text, v.R.RatString())
+		case Complex:
+			text = append(text, formatComplex(v))
+		case Vector:
+			text = append(text, joinVector(v, func(e Var) string { return source([]Var{e}) }))
+		case IP:
+			text = append(text, formatIP(v))
+		case String:
+			text = append(text, strconv.Quote(string(v.B)))
+		case Code:
+			if v.V != "" {
+				text = append(text, v.V)
+			} else {
+				text = append(text, formatCode(v.Code))
+			}
+		case Variable:
+			text = append(text, v.V)
+		case Assignment:
+			text = append(text, v.V+"=")
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+// formatCode formats the body of a quotation, like [ 1 + ].
+func formatCode(body []Var) string {
+	if len(body) == 0 {
+		return "[ ]"
+	}
+	return "[ " + source(body) + " ]"
+}
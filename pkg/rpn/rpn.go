@@ -0,0 +1,593 @@
+// Package rpn implements the reverse polish notation calculator behind the
+// rpn command, so it can be embedded in other programs.
+//
+// Each Interpreter owns its stack, variables, display mode and keyword
+// table, so several of them can live in the same process.
+package rpn
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Type int
+
+const (
+	Number Type = iota
+	Variable
+	String
+	Assignment
+	Code
+	Rational
+	Complex
+	Vector
+	IP
+)
+
+func (t Type) String() string {
+	switch t {
+	case Number:
+		return "Number"
+	case Variable:
+		return "Variable"
+	case String:
+		return "String"
+	case Assignment:
+		return "Assignment"
+	case Code:
+		return "Code"
+	case Rational:
+		return "Rational"
+	case Complex:
+		return "Complex"
+	case Vector:
+		return "Vector"
+	case IP:
+		return "IP"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+type Var struct {
+	Type   Type       // type of the thing
+	V      string     // Variable
+	F      *big.Float // Float, or real part of a Complex
+	I      *big.Float // imaginary part of a Complex
+	R      *big.Rat   // Rational
+	B      []byte     // "String", or the address of an IP
+	Code   []Var      // Code
+	Elems  []Var      // Vector, its numbers or the rows of a matrix
+	Prefix int        // IP, length of the network prefix, -1 for an address
+	Pos    int        // position of the lexeme in its line
+
+	prog []instr // Code, compiled
+	lit  string  // Number, Rational or Complex, the literal parsed into it
+}
+
+func (v Var) String() string {
+	switch v.Type {
+	case Number:
+		return v.F.String() + ":Number"
+	case Variable:
+		return v.V + ":Variable"
+	case Code:
+		if len(v.Code) != 0 {
+			return v.V + ":Code" + fmt.Sprint(v.Code)
+		}
+		return v.V + ":Code"
+	case Assignment:
+		return v.V + ":Assignment"
+	case Rational:
+		return v.R.RatString() + ":Rational"
+	case String:
+		return strconv.Quote(string(v.B)) + ":String"
+	case Complex:
+		return formatComplex(v) + ":Complex"
+	case Vector:
+		return joinVector(v, func(e Var) string { return fmt.Sprint(e) }) + ":Vector"
+	case IP:
+		return formatIP(v) + ":IP"
+	}
+	return ""
+}
+
+// x of interally eXecutable, c of constant, m of macro, a of assignment, q of
+// quotation, f of words followed by a file name
+var defaultKeyWords = map[string]string{
+	// Arithmetic Operators
+
+	"+":   "x", // Add
+	"-":   "x", // Subtract
+	"*":   "x", // Multiply
+	"/":   "x", // Divide
+	"cla": "x", // Clear the stack and variables
+	"clr": "x", // Clear the stack
+	"clv": "x", // Clear the variables
+	"!":   "x", // Boolean NOT
+	"%":   "x", // Modulus, with the sign of the dividend
+	"++":  "x", // Increment
+	"--":  "x", // Decrement
+
+	// Bitwise Operators
+
+	"&":  "x", // Bitwise AND
+	"|":  "x", // Bitwise OR
+	"^":  "x", // Bitwise XOR
+	"~":  "x", // Bitwise NOT
+	"<<": "x", // Bitwise shift left
+	">>": "x", // Bitwise shift right
+
+	// Boolean Operators
+
+	"&&": "x", // Boolean AND
+	"||": "x", // Boolean OR
+	"^^": "x", // Boolean XOR
+
+	// Comparison Operators
+
+	"!=": "x", // Not equal to
+	"<":  "x", // Less than
+	"<=": "x", // Less than or equal to
+	"==": "x", // Equal to
+	">":  "x", // Greater than
+	">=": "x", // Greater than or equal to
+
+	// Trigonometric Functions
+
+	"acos": "x", // Arc Cosine
+	"asin": "x", // Arc Sine
+	"atan": "x", // Arc Tangent
+	"cos":  "x", // Cosine
+	"cosh": "x", // Hyperbolic Cosine
+	"sin":  "x", // Sine
+	"sinh": "x", // Hyperbolic Sine
+	"tan":  "x", // Tangent
+	"tanh": "x", // Hyperbolic tangent
+
+	// Numeric Utilities
+
+	"ceil":  "x", // Ceiling
+	"floor": "x", // Floor
+	"round": "x", // Round
+	"ip":    "x", // Integer part
+	"fp":    "x", // Floating part
+	"sign":  "x", // Push -1, 0, or 0 depending on the sign
+	"abs":   "x", // Absolute value, or modulus of a complex number
+	"max":   "x", // Max
+	"min":   "x", // Min
+
+	// Statistics, over the whole stack, or the top n items for the words
+	// ending in n, e.g. '1 2 3 4 mean' or '1 2 3 4 2 meann'
+
+	"sum":         "x", // Sum
+	"sumn":        "x", // Sum of the top n items
+	"mean":        "x", // Arithmetic mean
+	"meann":       "x", // Arithmetic mean of the top n items
+	"median":      "x", // Median, the mean of the two middle items for an even count
+	"mediann":     "x", // Median of the top n items
+	"mode":        "x", // Most frequent value, the smallest one on ties
+	"moden":       "x", // Most frequent of the top n items
+	"var":         "x", // Sample variance
+	"varn":        "x", // Sample variance of the top n items
+	"pvar":        "x", // Population variance
+	"pvarn":       "x", // Population variance of the top n items
+	"stdev":       "x", // Sample standard deviation
+	"stdevn":      "x", // Sample standard deviation of the top n items
+	"pstdev":      "x", // Population standard deviation
+	"pstdevn":     "x", // Population standard deviation of the top n items
+	"range":       "x", // Largest minus smallest item
+	"rangen":      "x", // Range of the top n items
+	"percentile":  "x", // pth percentile, interpolated, e.g. '1 2 3 4 90 percentile'
+	"percentilen": "x", // pth percentile of the top n items, e.g. '1 2 3 4 90 4 percentilen'
+	"minn":        "x", // Smallest of the top n items, 'depth minn' for the whole stack
+	"maxn":        "x", // Largest of the top n items
+	"count":       "x", // Replace the stack with its number of items
+
+	// Fitting, of n x y pairs, the model of the last fit is stored in the
+	// model variable, e.g. '1 3 2 5 3 7 3 linreg 10 predict'
+
+	"linreg":  "x", // Push the slope, intercept and r² of the least squares line
+	"polyfit": "x", // Push the coefficients, highest degree first, and r² of a polynomial of degree d, e.g. 'pairs... 2 n polyfit'
+	"expfit":  "x", // Push a, b and r² of y = a e**(bx)
+	"logfit":  "x", // Push a, b and r² of y = a + b ln x
+	"predict": "x", // Run the model of the last fit on x
+
+	// Rationals
+
+	"rat":    "x", // Parse the numbers of the next lines as exact rationals, then '1 3 / 3 *' is 1
+	"float":  "x", // Parse the numbers of the next lines as floats (default)
+	">rat":   "x", // Convert a number to a rational, exactly
+	">float": "x", // Convert a rational to a float
+
+	// Complex Numbers, written like 3+4i or 2i, words on reals stay real so
+	// -1 sqrt fails and -1+0i sqrt is 0+1i
+
+	"cmplx": "x", // Make a complex number, 'a b cmplx' is a+bi
+	"re":    "x", // Real part
+	"im":    "x", // Imaginary part
+	"arg":   "x", // Argument, the angle of a complex number
+	"conj":  "x", // Complex conjugate
+	"polar": "x", // Convert a complex number to its modulus and argument
+	"rect":  "x", // Convert a modulus and argument to a complex number
+
+	// Linear Algebra, vectors like [1 2 3] and matrices like [[1 2] [3 4]],
+	// the arithmetic words apply to each of their elements
+
+	"dot":       "x", // Dot product of two vectors
+	"cross":     "x", // Cross product of two vectors of 3 numbers
+	"transpose": "x", // Transpose a matrix, a vector becomes a column
+	"det":       "x", // Determinant of a square matrix
+	"inv":       "x", // Inverse of a square matrix
+	"matmul":    "x", // Matrix product, a vector is a row on the left and a column on the right
+	"solve":     "x", // Solve A x = b for x, e.g. '[[2 1] [1 3]] [3 5] solve'
+	"norm":      "x", // Euclidean norm of a vector, Frobenius norm of a matrix
+	"identity":  "x", // n×n identity matrix, e.g. '3 identity'
+
+	// Strings, written like "hello\tworld", characters are counted in runes
+
+	"concat":  "x", // Concatenate two strings
+	"len":     "x", // Length of a string
+	"substr":  "x", // Substring from a start index and a length, e.g. '"hello" 1 3 substr' is "ell"
+	"upper":   "x", // Upper case
+	"lower":   "x", // Lower case
+	"split":   "x", // Split a string at a separator, pushing the parts and their count
+	"join":    "x", // Join n strings with a separator, e.g. '"a" "b" 2 "," join'
+	"find":    "x", // Index of a substring, or -1
+	"replace": "x", // Replace every occurrence of a substring, e.g. '"a-b" "-" "+" replace'
+	"str>num": "x", // Parse a string as a number
+	"num>str": "x", // Format a number in the current display mode
+	"format":  "x", // Format items with a Go fmt format, e.g. '3.14159 "%.2f" format'
+	"print":   "x", // Print the top item, strings without quotes
+
+	// Display Modes
+
+	"hex": "x", // Switch display mode to hexadecimal
+	"dec": "x", // Switch display mode to decimal (default)
+	"bin": "x", // Switch display mode to binary
+	"oct": "x", // Switch display mode to octal
+
+	// Precision
+
+	"prec":  "x", // Set the mantissa bits of numbers and results, e.g. '256 prec'
+	"rmode": "x", // Set the rounding mode: 0 nearest even, 1 nearest away, 2 zero, 3 away from zero, 4 -inf, 5 +inf
+
+	// IEEE-754, of f16 (half), bf16 (bfloat16), f32 (single) and f64
+	// (double), rounding to nearest even
+
+	"f16>bits":   "x", // Bits of a number as an f16, e.g. '1.5 f16>bits hex'
+	"bf16>bits":  "x", // Bits of a number as a bf16
+	"f32>bits":   "x", // Bits of a number as an f32
+	"f64>bits":   "x", // Bits of a number as an f64
+	"bits>f16":   "x", // Number of the bits of an f16, e.g. '0x3e00 bits>f16'
+	"bits>bf16":  "x", // Number of the bits of a bf16
+	"bits>f32":   "x", // Number of the bits of an f32
+	"bits>f64":   "x", // Number of the bits of an f64
+	">f16":       "x", // Round a number to an f16
+	">bf16":      "x", // Round a number to a bf16
+	">f32":       "x", // Round a number to an f32, e.g. '0.1 >f32'
+	">f64":       "x", // Round a number to an f64
+	"f16>parts":  "x", // Push the sign, biased exponent and stored mantissa of a number as an f16
+	"bf16>parts": "x", // Push the sign, biased exponent and stored mantissa as a bf16
+	"f32>parts":  "x", // Push the sign, biased exponent and stored mantissa as an f32
+	"f64>parts":  "x", // Push the sign, biased exponent and stored mantissa as an f64
+	"f16>str":    "x", // Describe the sign, exponent and mantissa of a number as an f16
+	"bf16>str":   "x", // Describe a number as a bf16
+	"f32>str":    "x", // Describe a number as an f32, e.g. 'pi f32>str print'
+	"f64>str":    "x", // Describe a number as an f64
+
+	// Word Size, arithmetic and bitwise words compute on integers wrapped
+	// to it, and hex, oct and bin show two's complement
+
+	"ws":       "x", // Set the word size in bits, 0 for unbounded integers (default), e.g. '16 ws'
+	"signed":   "x", // Wrap to two's complement signed integers
+	"unsigned": "x", // Wrap to unsigned integers (default)
+
+	// Constants
+
+	"e":    "c", // Push e
+	"pi":   "c", // Push Pi
+	"rand": "c", // Generate a random number
+
+	// Mathematic Functions
+
+	"exp":  "x", // Exponential function, e**x
+	"fact": "x", // Factorial
+	"sqrt": "x", // Square Root
+	"ln":   "x", // Natural Logarithm
+	"log":  "x", // Logarithm
+	"pow":  "x", // Raise a number to a power
+	"**":   "x", // Raise a number to a power
+
+	// Number Theory, of integers
+
+	"mod":       "x", // Floored modulus, with the sign of the divisor, e.g. '-7 3 mod' is 2
+	"emod":      "x", // Euclidean modulus, never negative, e.g. '-7 -3 emod' is 2
+	"gcd":       "x", // Greatest common divisor
+	"lcm":       "x", // Least common multiple
+	"modpow":    "x", // a**b mod m, e.g. '4 13 497 modpow'
+	"modinv":    "x", // Inverse of a modulo m, e.g. '3 11 modinv'
+	"isprime":   "x", // Whether an integer is prime
+	"nextprime": "x", // Smallest prime greater than an integer
+	"factor":    "x", // Push the prime factors in ascending order and their count
+	"totient":   "x", // Euler's totient
+	"binom":     "x", // Binomial coefficient, n choose k, e.g. '5 2 binom'
+	"fib":       "x", // n'th Fibonacci number, e.g. '10 fib'
+	"isqrt":     "x", // Integer square root, rounded down
+	"ilog2":     "x", // Integer base 2 logarithm, rounded down
+
+	// Networking
+
+	"hnl":       "x", // Host to network long, the 4 bytes of an integer, big-endian
+	"hns":       "x", // Host to network short, the 2 bytes of an integer, big-endian
+	"nhl":       "x", // Network to host long, the integer of 4 big-endian bytes
+	"nhs":       "x", // Network to host short, the integer of 2 big-endian bytes
+	"netmask":   "x", // Netmask of a network, e.g. '192.168.1.0/24 netmask'
+	"network":   "x", // Network of an address, its host bits cleared, e.g. '192.168.1.77/24 network'
+	"broadcast": "x", // Broadcast address, or last address in IPv6, of a network
+	"hosts":     "x", // Number of host addresses of a network, without the network and broadcast addresses in IPv4
+	"contains":  "x", // Whether a network contains an address or network, e.g. '10.0.0.0/8 10.1.2.3 contains'
+	"subnet":    "x", // Split a network into the networks of prefix n, e.g. '10.0.0.0/24 26 subnet'
+	"supernet":  "x", // Network one bit shorter, e.g. '192.168.1.0/24 supernet'
+	"ip>int":    "x", // Integer of an address
+	"int>ip":    "x", // IPv4 address of an integer, or IPv6 past 32 bits
+	"bswap16":   "x", // Swap the 2 bytes of an integer, e.g. '0x1234 bswap16 hex'
+	"bswap32":   "x", // Swap the 4 bytes of an integer
+	"bswap64":   "x", // Swap the 8 bytes of an integer
+	"htons":     "x", // Host to network order of a 16 bit integer
+	"htonl":     "x", // Host to network order of a 32 bit integer
+	"htonll":    "x", // Host to network order of a 64 bit integer
+	"ntohs":     "x", // Network to host order of a 16 bit integer
+	"ntohl":     "x", // Network to host order of a 32 bit integer
+	"ntohll":    "x", // Network to host order of a 64 bit integer
+	"pack":      "x", // Pack values into bytes with a format like Python's struct, e.g. '80 443 7 ">HHI" pack'
+	"unpack":    "x", // Unpack bytes with a format, e.g. '"PNG" "3s" unpack'
+
+	// Checksums and Encodings, of the bytes of strings
+
+	"crc32":    "x", // CRC-32 (IEEE), e.g. '"123456789" crc32 hex'
+	"crc16":    "x", // CRC-16/ARC
+	"adler32":  "x", // Adler-32
+	"fletcher": "x", // Fletcher-16
+	"inetsum":  "x", // Internet checksum (RFC 1071), e.g. '0x4500 0x0030 ">HH" pack inetsum'
+	"fnv":      "x", // 32 bit FNV-1a hash
+	"fnv64":    "x", // 64 bit FNV-1a hash
+	"md5":      "x", // MD5 digest, as bytes
+	"sha1":     "x", // SHA-1 digest, as bytes
+	"sha256":   "x", // SHA-256 digest, as bytes, e.g. '"abc" sha256 >hex'
+	">hex":     "x", // Encode bytes as hex text
+	"hex>":     "x", // Decode hex text to bytes, e.g. '"cafe" hex>'
+	">base64":  "x", // Encode bytes as base64 text
+	"base64>":  "x", // Decode base64 text to bytes
+	">base32":  "x", // Encode bytes as base32 text
+	"base32>":  "x", // Decode base32 text to bytes
+
+	// Stack Manipulation
+
+	"pick":   "x", // Pick the -n'th item from the stack
+	"repeat": "x", // Repeat an operation n times, e.g. '3 repeat +'
+	"depth":  "x", // Push the current stack depth
+	"drop":   "x", // Drops the top item from the stack
+	"dropn":  "x", // Drops n items from the stack
+	"dup":    "x", // Duplicates the top stack item
+	"dupn":   "x", // Duplicates the top n stack items in order
+	"roll":   "x", // Roll the stack upwards by n
+	"rolld":  "x", // Roll the stack downwards by n
+	"stack":  "x", // Toggles stack display from horizontal to vertical
+	"swap":   "x", // Swap the top 2 stack items
+
+	// Control Flow, with quotations like [ 1 + ] that are pushed unevaluated
+
+	"[":      "q", // Start a quotation
+	"]":      "q", // End a quotation
+	"call":   "x", // Run a quotation, e.g. '3 [ dup * ] call'
+	"if":     "x", // Run a quotation if the condition isn't 0, e.g. 'dup 0 < [ -1 * ] if'
+	"ifelse": "x", // Run the first quotation if the condition isn't 0, else the second
+	"while":  "x", // Run the body while the condition leaves non 0, e.g. '[ dup 1 > ] [ 2 / ] while'
+	"times":  "x", // Run a quotation n times, e.g. '1 10 [ 2 * ] times'
+	"for":    "x", // Run a quotation for each integer from a to b, pushing it first, e.g. '0 1 10 [ + ] for'
+
+	// Macros and Variables
+
+	"macro": "m", // Defines a macro, e.g. 'macro kib 1024 *'
+	"=":     "a", // Assigns a variable, e.g. '1024 x='
+
+	// Sessions
+
+	"save": "f", // Save the stack, variables, macros, mode and precision to a file, e.g. 'save monday.json'
+	"load": "f", // Load a file written by save
+
+	"include": "f", // Evaluate the lines of a file, e.g. 'include units.rpn'
+
+	"tee":   "f", // Also append the stacks and printed text to a file, e.g. 'tee calc.log'
+	"untee": "x", // Stop copying to the file of tee
+
+	// History, of the lines recorded by Checkpoint
+
+	"undo": "x", // Go back to the stack, variables and display mode before the last line
+	"redo": "x", // Go forward to the line undone
+
+	// Other
+
+	"help":   "x", // Print the help message
+	"status": "x", // Print the display mode, precision and rounding mode
+	"exit":   "x", // Exit the calculator
+	"debug":  "x", // toggle debug mode
+}
+
+// DefaultPrec is the precision of new interpreters, the one big.Float
+// gives to parsed numbers by default.
+const DefaultPrec = 64
+
+// Interpreter evaluates lines of rpn code against its own stack and
+// variables. The file opened by the tee word stays open until untee, or
+// Close once the interpreter is no longer used.
+type Interpreter struct {
+	Mode     string // display mode: dec, hex, bin or oct
+	Vertical bool   // print the stack one item per line
+	Exit     bool   // set once the exit word has been evaluated
+	Debug    bool   // trace parsing and evaluation
+
+	Prec         uint             // mantissa bits of parsed numbers and results
+	RoundingMode big.RoundingMode // rounding of parsed numbers and results
+	Rat          bool             // parse numbers as exact rationals
+
+	WordSize uint // bits the integer words wrap to, 0 for unbounded
+	Signed   bool // wrap to two's complement signed integers
+
+	UndoDepth int // lines that can be undone, see Checkpoint
+
+	Out   io.Writer // where the help message is written
+	Trace io.Writer // where the debug traces are written
+
+	stack    []Var
+	vars     map[string]Var
+	keyWords map[string]string
+	depth    int      // nesting of the macros and quotations being run
+	includes []string // paths of the files being included
+	teeFile  *os.File // where the tee word copies the output
+	teeLine  *os.File // teeFile when the line being evaluated started
+
+	history []snapshot // states recorded by Checkpoint, oldest first
+	cur     int        // position of the current state in history
+	undone  bool       // set by a line that only did undo or redo
+	changed bool       // set by the words other than undo and redo since the last Checkpoint
+}
+
+// New returns an Interpreter with an empty stack, in decimal mode, writing
+// help to os.Stdout and traces to os.Stderr.
+func New() *Interpreter {
+	in := &Interpreter{
+		Mode:      "dec",
+		Prec:      DefaultPrec,
+		UndoDepth: DefaultUndoDepth,
+		cur:       -1,
+		Out:       os.Stdout,
+		Trace:     os.Stderr,
+		stack:     make([]Var, 0),
+		vars:      make(map[string]Var, 0),
+		keyWords:  make(map[string]string, len(defaultKeyWords)),
+	}
+	for k, v := range defaultKeyWords {
+		in.keyWords[k] = v
+	}
+	in.Checkpoint()
+	return in
+}
+
+// Eval parses and evaluates one line of input. When it fails the stack,
+// variables, display mode and precision are left as they were before the
+// line.
+func (in *Interpreter) Eval(line string) error {
+	if in.Debug {
+		fmt.Fprintln(in.Trace, "New line")
+	}
+	lex, err := Lex(line)
+	if err != nil {
+		return err
+	}
+	stackR, err := in.Parse(lex)
+	if err != nil {
+		return err
+	}
+	if in.Debug {
+		fmt.Fprintln(in.Trace, "stack: ", stackR)
+	}
+	prog, err := in.compile(stackR)
+	if err != nil {
+		return err
+	}
+	stack := append([]Var(nil), in.stack...)
+	vars, keyWords := in.Vars(), in.keyWords
+	mode, vertical, prec, rmode, rat := in.Mode, in.Vertical, in.Prec, in.RoundingMode, in.Rat
+	ws, signed := in.WordSize, in.Signed
+	cur, undone, changed := in.cur, in.undone, in.changed
+	in.teeLine = in.teeFile
+	in.keyWords = make(map[string]string, len(keyWords))
+	for k, v := range keyWords {
+		in.keyWords[k] = v
+	}
+	if err = in.run(prog, -1); err != nil {
+		in.stack, in.vars, in.keyWords = stack, vars, keyWords
+		in.Mode, in.Vertical, in.Exit = mode, vertical, false
+		in.Prec, in.RoundingMode, in.Rat = prec, rmode, rat
+		in.WordSize, in.Signed = ws, signed
+		in.cur, in.undone, in.changed = cur, undone, changed
+	}
+	in.endTee(err != nil)
+	return err
+}
+
+// Stack returns a copy of the current stack, bottom first.
+func (in *Interpreter) Stack() []Var {
+	return append([]Var(nil), in.stack...)
+}
+
+// Vars returns a copy of the variables and macros.
+func (in *Interpreter) Vars() map[string]Var {
+	vars := make(map[string]Var, len(in.vars))
+	for k, v := range in.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// newFloat returns a zero with the interpreter's precision and rounding
+// mode.
+func (in *Interpreter) newFloat() *big.Float {
+	return newFloat(in.Prec).SetMode(in.RoundingMode)
+}
+
+// round rounds x to the interpreter's precision.
+func (in *Interpreter) round(x *big.Float) *big.Float {
+	return in.newFloat().Set(x)
+}
+
+func (in *Interpreter) isKeyword(s string) bool {
+	_, ok := in.keyWords[s]
+	return ok
+}
+
+// Keywords returns the sorted list of words known to the interpreter,
+// including user-defined macros.
+func (in *Interpreter) Keywords() []string {
+	words := make([]string, 0, len(in.keyWords))
+	for k := range in.keyWords {
+		words = append(words, k)
+	}
+	sort.Strings(words)
+	return words
+}
+
+func (in *Interpreter) help() {
+	fmt.Fprintln(in.Tee(in.Out), "words:", strings.Join(in.Keywords(), " "))
+}
+
+// status prints the display settings and precision, depth is the size of the
+// stack.
+func (in *Interpreter) status(depth int) {
+	layout := "horizontal"
+	if in.Vertical {
+		layout = "vertical"
+	}
+	numbers := "float"
+	if in.Rat {
+		numbers = "rat"
+	}
+	ws := "unbounded"
+	if in.WordSize > 0 {
+		ws = fmt.Sprintf("%d bits", in.WordSize)
+		if in.Signed {
+			ws += " signed"
+		} else {
+			ws += " unsigned"
+		}
+	}
+	fmt.Fprintf(in.Tee(in.Out), "mode %s, %s stack, %s numbers, prec %d bits (~%d digits), rmode %v, ws %s, depth %d, %d variables\n",
+		in.Mode, layout, numbers, in.Prec, int(float64(in.Prec)*math.Log10(2)), in.RoundingMode, ws, depth, len(in.vars))
+}
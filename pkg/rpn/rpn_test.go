@@ -0,0 +1,108 @@
+package rpn
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// newTest returns an Interpreter that writes its output nowhere.
+func newTest() *Interpreter {
+	in := New()
+	in.Out, in.Trace = ioutil.Discard, ioutil.Discard
+	return in
+}
+
+// evalLines evaluates the lines on a new Interpreter, failing the test when
+// one fails.
+func evalLines(t *testing.T, lines ...string) *Interpreter {
+	t.Helper()
+	in := newTest()
+	for _, line := range lines {
+		if err := in.Eval(line); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+	return in
+}
+
+// show formats the stack the way PrintStack does, separated by spaces.
+func show(in *Interpreter) string {
+	items := make([]string, len(in.stack))
+	for i, v := range in.stack {
+		items[i] = in.Format(v)
+	}
+	return strings.Join(items, " ")
+}
+
+// evalTest is a line and the stack it leaves on a new Interpreter.
+type evalTest struct {
+	line, want string
+}
+
+func testEval(t *testing.T, tests []evalTest) {
+	t.Helper()
+	for _, test := range tests {
+		in := newTest()
+		if err := in.Eval(test.line); err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+		if got := show(in); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.line, got, test.want)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	testEval(t, []evalTest{
+		{"1 2 +", "3"},
+		{"10 4 -", "6"},
+		{"6 7 *", "42"},
+		{"1 4 /", "0.25"},
+		{"2 10 **", "1024"},
+		{"1 2 swap", "2 1"},
+		{"1 dup", "1 1"},
+		{"1 2 drop", "1"},
+		{"1 2 3 depth", "1 2 3 3"},
+		{"1 2 3 2 pick", "1 2 3 2"},
+		{"1 2 3 1 roll", "3 1 2"},
+		{"1 2 3 1 rolld", "2 3 1"},
+		{"1 2 2 dupn", "1 2 1 2"},
+		{"1 2 3 2 dropn", "1"},
+		{"2 x= x x *", "4"},
+		{"255 hex", "0xff"},
+		{"5 bin", "0b101"},
+		{"8 oct", "010"},
+		{"1 2 clr", ""},
+	})
+}
+
+func TestMacro(t *testing.T) {
+	in := evalLines(t, "macro sq dup *", "3 sq")
+	if got := show(in); got != "9" {
+		t.Errorf("got %s, want 9", got)
+	}
+}
+
+func TestInterpretersAreIndependent(t *testing.T) {
+	a, b := newTest(), newTest()
+	if err := a.Eval("1 x= macro two 2 hex"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Eval("x"); err == nil {
+		t.Error("the variable of one interpreter is known to another")
+	}
+	if b.Mode != "dec" || b.isKeyword("two") {
+		t.Errorf("the mode or macros of one interpreter changed another: %s %v", b.Mode, b.Keywords())
+	}
+}
+
+func TestStackIsACopy(t *testing.T) {
+	in := evalLines(t, "1 2")
+	s := in.Stack()
+	s[0] = Var{Type: String, B: []byte("changed")}
+	if got := show(in); got != "1 2" {
+		t.Errorf("changing the copy changed the stack to %s", got)
+	}
+}
@@ -0,0 +1,288 @@
+package rpn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// SessionVersion is the version of the session files written by Save.
+const SessionVersion = 1
+
+// session is the layout of the session files, in JSON.
+type session struct {
+	Version  int             `json:"version"`
+	Mode     string          `json:"mode"`
+	Vertical bool            `json:"vertical"`
+	Prec     uint            `json:"prec"`
+	RMode    int             `json:"rmode"`
+	Rat      bool            `json:"rat"`
+	WordSize uint            `json:"ws,omitempty"`
+	Signed   bool            `json:"signed,omitempty"`
+	Stack    []item          `json:"stack"`
+	Vars     map[string]item `json:"vars"`
+}
+
+// item is a stack item or variable of a session file. Numbers are written
+// in decimal with as many digits as their precision needs.
+type item struct {
+	Type  string `json:"type"`            // Number, Rational, Complex, String, Vector, IP, Code or Macro
+	Value string `json:"value,omitempty"` // the number, text or source code
+	Imag  string `json:"imag,omitempty"`  // imaginary part of a Complex
+	Prec  uint   `json:"prec,omitempty"`  // mantissa bits of a Number or Complex
+	Bytes []byte `json:"bytes,omitempty"` // a String that isn't UTF-8
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(name string) string {
+	if name == "~" || strings.HasPrefix(name, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, name[1:])
+		}
+	}
+	return name
+}
+
+// Save writes the stack, variables, macros, display mode and precision to
+// the file name.
+func (in *Interpreter) Save(name string) error {
+	s := session{
+		Version:  SessionVersion,
+		Mode:     in.Mode,
+		Vertical: in.Vertical,
+		Prec:     in.Prec,
+		RMode:    int(in.RoundingMode),
+		Rat:      in.Rat,
+		WordSize: in.WordSize,
+		Signed:   in.Signed,
+		Stack:    make([]item, 0, len(in.stack)),
+		Vars:     make(map[string]item, len(in.vars)),
+	}
+	for _, v := range in.stack {
+		s.Stack = append(s.Stack, saveItem(v))
+	}
+	for k, v := range in.vars {
+		s.Vars[k] = saveItem(v)
+	}
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	if err := os.WriteFile(expandHome(name), append(b, '\n'), 0o644); err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	return nil
+}
+
+func saveItem(v Var) item {
+	switch v.Type {
+	case Number:
+		return item{Type: v.Type.String(), Value: v.F.Text('g', -1), Prec: v.F.Prec()}
+	case Rational:
+		return item{Type: v.Type.String(), Value: v.R.RatString()}
+	case Complex:
+		return item{Type: v.Type.String(), Value: v.F.Text('g', -1), Imag: v.I.Text('g', -1), Prec: v.F.Prec()}
+	case String:
+		if utf8.Valid(v.B) {
+			return item{Type: v.Type.String(), Value: string(v.B)}
+		}
+		return item{Type: v.Type.String(), Bytes: v.B}
+	case Vector:
+		return item{Type: v.Type.String(), Value: vectorSource(v), Prec: vectorPrec(v)}
+	case IP:
+		return item{Type: v.Type.String(), Value: formatIP(v)}
+	case Code:
+		if v.V != "" {
+			return item{Type: "Macro", Value: source(v.Code)}
+		}
+		return item{Type: v.Type.String(), Value: formatCode(v.Code)}
+	}
+	return item{Type: v.Type.String(), Value: v.V}
+}
+
+// vectorSource formats a vector with its rationals as fractions, like 2/1,
+// so they parse back as rationals whatever the mode.
+func vectorSource(v Var) string {
+	return joinVector(v, func(e Var) string {
+		if e.Type == Rational {
+			return e.R.String()
+		}
+		return source([]Var{e})
+	})
+}
+
+// vectorPrec returns the largest precision of the Numbers of a vector, 0
+// when it has none.
+func vectorPrec(v Var) uint {
+	prec := uint(0)
+	for _, e := range v.Elems {
+		p := vectorPrec(e)
+		if e.Type == Number {
+			p = e.F.Prec()
+		}
+		if p > prec {
+			prec = p
+		}
+	}
+	return prec
+}
+
+// Load replaces the stack, variables, macros, display mode and precision
+// with the ones saved in the file name.
+func (in *Interpreter) Load(name string) error {
+	b, err := os.ReadFile(expandHome(name))
+	if err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	var s session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	if s.Version < 1 || s.Version > SessionVersion {
+		return ErrFile{Name: name, Err: fmt.Errorf("unsupported version %d", s.Version)}
+	}
+	if s.WordSize > maxWordSize {
+		return ErrFile{Name: name, Err: fmt.Errorf("bad word size %d", s.WordSize)}
+	}
+	if s.Prec == 0 || s.Prec > big.MaxPrec || s.RMode < 0 || s.RMode > int(big.ToPositiveInf) {
+		return ErrFile{Name: name, Err: errors.New("bad precision or rounding mode")}
+	}
+	switch s.Mode {
+	case "dec", "hex", "bin", "oct":
+	default:
+		return ErrFile{Name: name, Err: fmt.Errorf("bad mode %q", s.Mode)}
+	}
+	// the macros are keywords before any code is parsed, as they may call
+	// each other, and the numbers in it are parsed with the saved precision
+	// and mode
+	keyWords, rat := in.keyWords, in.Rat
+	in.keyWords = make(map[string]string, len(keyWords))
+	for k, v := range keyWords {
+		if m, ok := in.vars[k]; ok && m.Type == Code && m.V != "" {
+			if _, ok := defaultKeyWords[k]; !ok {
+				continue
+			}
+		}
+		in.keyWords[k] = v
+	}
+	for k, it := range s.Vars {
+		if it.Type == "Macro" {
+			in.keyWords[k] = "x"
+		}
+	}
+	prec, rmode := in.Prec, in.RoundingMode
+	in.Prec, in.RoundingMode, in.Rat = s.Prec, big.RoundingMode(s.RMode), s.Rat
+	stack, vars, err := in.loadItems(s)
+	if err != nil {
+		in.keyWords = keyWords
+		in.Prec, in.RoundingMode, in.Rat = prec, rmode, rat
+		return ErrFile{Name: name, Err: err}
+	}
+	in.stack, in.vars = stack, vars
+	in.Mode, in.Vertical = s.Mode, s.Vertical
+	in.WordSize, in.Signed = s.WordSize, s.Signed
+	return nil
+}
+
+func (in *Interpreter) loadItems(s session) ([]Var, map[string]Var, error) {
+	vars := make(map[string]Var, len(s.Vars))
+	for k, it := range s.Vars {
+		v, err := in.loadItem(it)
+		if err != nil {
+			return nil, nil, fmt.Errorf("variable %s: %v", k, err)
+		}
+		if it.Type == "Macro" {
+			v.V = k
+		}
+		vars[k] = v
+	}
+	stack := make([]Var, 0, len(s.Stack))
+	for i, it := range s.Stack {
+		v, err := in.loadItem(it)
+		if err != nil {
+			return nil, nil, fmt.Errorf("stack item %d: %v", i, err)
+		}
+		stack = append(stack, v)
+	}
+	return stack, vars, nil
+}
+
+func (in *Interpreter) loadItem(it item) (Var, error) {
+	switch it.Type {
+	case "Number":
+		f, _, err := newFloat(it.Prec).Parse(it.Value, 10)
+		return Var{Type: Number, F: f}, err
+	case "Rational":
+		r, ok := new(big.Rat).SetString(it.Value)
+		if !ok {
+			return Var{}, fmt.Errorf("bad rational %q", it.Value)
+		}
+		return Var{Type: Rational, R: r}, nil
+	case "Complex":
+		re, _, err := newFloat(it.Prec).Parse(it.Value, 10)
+		if err != nil {
+			return Var{}, err
+		}
+		im, _, err := newFloat(it.Prec).Parse(it.Imag, 10)
+		return Var{Type: Complex, F: re, I: im}, err
+	case "String":
+		if it.Bytes != nil {
+			return Var{Type: String, B: it.Bytes}, nil
+		}
+		return Var{Type: String, B: []byte(it.Value)}, nil
+	case "IP":
+		v, ok := parseIP(it.Value)
+		if !ok {
+			return Var{}, fmt.Errorf("bad address %q", it.Value)
+		}
+		return v, nil
+	case "Code", "Macro", "Vector":
+		lex, err := Lex(it.Value)
+		if err != nil {
+			return Var{}, err
+		}
+		parse := in.Parse
+		if it.Type == "Vector" {
+			parse = in.parseVector(it.Prec)
+		}
+		code, err := parse(lex)
+		if err != nil {
+			return Var{}, err
+		}
+		if it.Type == "Macro" {
+			prog, err := in.compile(code)
+			return Var{Type: Code, Code: code, prog: prog}, err
+		}
+		if it.Type == "Vector" && (len(code) != 1 || code[0].Type != Vector) {
+			return Var{}, fmt.Errorf("bad vector %q", it.Value)
+		}
+		if it.Type == "Code" && (len(code) != 1 || code[0].Type != Code || code[0].V != "") {
+			return Var{}, fmt.Errorf("bad quotation %q", it.Value)
+		}
+		if it.Type == "Code" {
+			code[0].prog, err = in.compile(code[0].Code)
+		}
+		return code[0], err
+	}
+	return Var{}, fmt.Errorf("unknown type %q", it.Type)
+}
+
+// parseVector returns a Parse for the source of a saved vector, whose
+// numbers are parsed back with the precision they were saved with, and are
+// floats but for the fractions.
+func (in *Interpreter) parseVector(prec uint) func(lex []string) ([]Var, error) {
+	return func(lex []string) ([]Var, error) {
+		p, rmode, rat := in.Prec, in.RoundingMode, in.Rat
+		defer func() { in.Prec, in.RoundingMode, in.Rat = p, rmode, rat }()
+		if prec > 0 {
+			in.Prec = prec
+		}
+		in.RoundingMode, in.Rat = big.ToNearestEven, false
+		return in.Parse(lex)
+	}
+}
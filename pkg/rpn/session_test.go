@@ -0,0 +1,86 @@
+package rpn
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// saveLoad saves the state after the lines and loads it in a new
+// Interpreter.
+func saveLoad(t *testing.T, lines ...string) *Interpreter {
+	t.Helper()
+	name := strconv.Quote(filepath.Join(t.TempDir(), "session.json"))
+	evalLines(t, append(lines, "save "+name)...)
+	return evalLines(t, "load "+name)
+}
+
+func TestSession(t *testing.T) {
+	in := saveLoad(t, `1 1/3 3+4i "text" [1 2] 10.0.0.0/8 [ dup * ] 7 x=`, "macro sq dup *", "hex stack 100 prec 2 rmode 16 ws signed")
+	if got, want := show(in), `0x1 0x0 3+4i "text" [0x1 0x2] 10.0.0.0/8 [ dup * ]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if r := in.stack[1]; r.Type != Rational || r.R.RatString() != "1/3" {
+		t.Errorf("1/3 came back as %v", r)
+	}
+	if in.Mode != "hex" || !in.Vertical || in.Prec != 100 || in.RoundingMode != 2 || in.WordSize != 16 || !in.Signed {
+		t.Errorf("the settings weren't restored: %s %v %d %v %d %v", in.Mode, in.Vertical, in.Prec, in.RoundingMode, in.WordSize, in.Signed)
+	}
+	if err := in.Eval("clr dec x 3 sq"); err != nil {
+		t.Fatal(err)
+	}
+	if got := show(in); got != "7 9" {
+		t.Errorf("the variable and macro give %s, want 7 9", got)
+	}
+}
+
+func TestSessionNumbers(t *testing.T) {
+	in := saveLoad(t, "256 prec pi [1 2] pi * rat [0.5 2] 64 prec")
+	if in.Prec != 64 {
+		t.Fatalf("prec is %d", in.Prec)
+	}
+	if p := in.stack[0].F.Prec(); p != 256 {
+		t.Errorf("pi came back with %d bits", p)
+	}
+	if e := in.stack[1].Elems[1]; e.Type != Number || e.F.Prec() != 256 {
+		t.Errorf("a 256 bit vector came back as %v with %d bits", e.Type, e.F.Prec())
+	}
+	if got := in.Format(in.stack[2]); got != "[1/2 2]" || in.stack[2].Elems[1].Type != Rational {
+		t.Errorf("a rational vector came back as %s", got)
+	}
+	in = saveLoad(t, "[1 2] rat")
+	if e := in.stack[0].Elems[0]; e.Type != Number {
+		t.Errorf("a float vector saved in rat mode came back as %v", e.Type)
+	}
+}
+
+func TestSessionBytes(t *testing.T) {
+	in := saveLoad(t, `0x1234 hns`)
+	if got := show(in); got != "<12 34>" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, text := range []string{
+		`not json`,
+		`{"version": 2, "mode": "dec", "prec": 64}`,
+		`{"version": 1, "mode": "dec", "prec": 0}`,
+		`{"version": 1, "mode": "base 3", "prec": 64}`,
+		`{"version": 1, "mode": "dec", "prec": 32, "rat": true, "stack": [{"type": "Rational", "value": "x"}]}`,
+	} {
+		name := filepath.Join(dir, "bad.json")
+		if err := ioutil.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		in := evalLines(t, "1")
+		if err := in.Eval("load " + strconv.Quote(name)); err == nil {
+			t.Errorf("%s: loaded", text)
+		}
+		if show(in) != "1" || in.Prec != 64 || in.Rat {
+			t.Errorf("%s: a failed load changed the state", text)
+		}
+	}
+}
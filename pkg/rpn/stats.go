@@ -0,0 +1,227 @@
+package rpn
+
+import (
+	"math/big"
+	"sort"
+)
+
+// stat is a statistics word. The words ending in n summarize the top n items
+// and the others the whole stack, below the args items they take first.
+type stat struct {
+	n     bool // the word takes the number of items from the top of the stack
+	pairs bool // the items are n x y pairs
+	args  int  // items taken before the data, like the p of percentile
+	min   int  // fewest data items
+	fn    func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error)
+}
+
+var stats = map[string]stat{}
+
+const maxInt = int(^uint(0) >> 1)
+
+func init() {
+	for k, s := range map[string]stat{
+		"sum":        {min: 1, fn: statSum},
+		"mean":       {min: 1, fn: statMean},
+		"median":     {min: 1, fn: statMedian},
+		"mode":       {min: 1, fn: statMode},
+		"var":        {min: 2, fn: variance(1, false)},
+		"pvar":       {min: 1, fn: variance(0, false)},
+		"stdev":      {min: 2, fn: variance(1, true)},
+		"pstdev":     {min: 1, fn: variance(0, true)},
+		"range":      {min: 1, fn: statRange},
+		"percentile": {args: 1, min: 1, fn: statPercentile},
+		"min":        {min: 1, fn: extreme(func(c int) bool { return c < 0 })},
+		"max":        {min: 1, fn: extreme(func(c int) bool { return c > 0 })},
+	} {
+		n := s
+		n.n = true
+		stats[k+"n"] = n
+		if k != "min" && k != "max" { // min and max of two items are ops
+			stats[k] = s
+		}
+	}
+	stats["count"] = stat{fn: func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+		return in.integer(big.NewInt(int64(len(data)))), nil
+	}}
+	for k, s := range stats {
+		words[k] = s.args
+		if s.n {
+			words[k]++
+		}
+	}
+}
+
+// stat applies s to the data items of the stack, in place of them and the
+// items s takes.
+func (in *Interpreter) stat(s stat) error {
+	size := len(in.stack) - s.args
+	if s.n {
+		n, err := in.count()
+		if err != nil {
+			return err
+		}
+		if n < s.min {
+			return ErrDomain{Value: big.NewInt(int64(n)).String()}
+		}
+		per := 1
+		if s.pairs {
+			per = 2
+		}
+		// n is compared before the multiplication, which could overflow
+		if n > (maxInt-s.args-1)/per {
+			return ErrDomain{Value: big.NewInt(int64(n)).String()}
+		}
+		if n > (len(in.stack)-s.args)/per {
+			return ErrStackUnderflow{Need: n*per + s.args + 1, Have: len(in.stack) + 1}
+		}
+		size = n * per
+	} else if size < s.min {
+		return ErrStackUnderflow{Need: s.min + s.args, Have: len(in.stack)}
+	}
+	items := in.stack[len(in.stack)-size-s.args:]
+	data, args := items[:size], items[size:]
+	xs := make([]*big.Float, len(data))
+	for i := range data {
+		x, err := in.toFloat(data[i])
+		if err != nil {
+			return err
+		}
+		xs[i] = x
+	}
+	o := op{len(items), func(in *Interpreter, _ []Var) ([]Var, error) { return s.fn(in, data, xs, args) }}
+	res, err := in.call(o, items)
+	if err != nil {
+		return err
+	}
+	in.stack = append(in.stack[:len(in.stack)-len(items)], res...)
+	return nil
+}
+
+// wp returns a zero with guard bits over the interpreter's precision, for
+// the intermediate results.
+func (in *Interpreter) wp() *big.Float {
+	return newFloat(in.Prec + guardBits)
+}
+
+func (in *Interpreter) sum(xs []*big.Float) *big.Float {
+	sum := in.wp()
+	for _, x := range xs {
+		sum.Add(sum, x)
+	}
+	return sum
+}
+
+func (in *Interpreter) mean(xs []*big.Float) *big.Float {
+	sum := in.sum(xs)
+	return sum.Quo(sum, new(big.Float).SetInt64(int64(len(xs))))
+}
+
+// sorted returns the values of xs in increasing order.
+func sorted(xs []*big.Float) []*big.Float {
+	s := append([]*big.Float(nil), xs...)
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Cmp(s[j]) < 0 })
+	return s
+}
+
+func statSum(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	return number(in.round(in.sum(xs))), nil
+}
+
+func statMean(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	return number(in.round(in.mean(xs))), nil
+}
+
+// statMedian returns the middle value, or the mean of the two middle ones.
+func statMedian(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	s := sorted(xs)
+	m := len(s) / 2
+	if len(s)%2 == 1 {
+		return number(in.round(s[m])), nil
+	}
+	z := in.wp().Add(s[m-1], s[m])
+	return number(in.round(z.Quo(z, big.NewFloat(2)))), nil
+}
+
+// statMode returns the most frequent value, the smallest one on ties.
+func statMode(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	s := sorted(xs)
+	best, bestRun := s[0], 0
+	for i := 0; i < len(s); {
+		j := i + 1
+		for j < len(s) && s[j].Cmp(s[i]) == 0 {
+			j++
+		}
+		if j-i > bestRun {
+			best, bestRun = s[i], j-i
+		}
+		i = j
+	}
+	return number(in.round(best)), nil
+}
+
+// variance returns the variance, or with sqrt the standard deviation, of the
+// sample when ddof is 1 and of the population when it is 0.
+func variance(ddof int, sqrt bool) func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	return func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+		m := in.mean(xs)
+		sum, d := in.wp(), in.wp()
+		for _, x := range xs {
+			d.Sub(x, m)
+			sum.Add(sum, d.Mul(d, d))
+		}
+		z := sum.Quo(sum, new(big.Float).SetInt64(int64(len(xs)-ddof)))
+		if sqrt {
+			var err error
+			if z, err = bigSqrt(z, in.Prec); err != nil {
+				return nil, err
+			}
+		}
+		return number(in.round(z)), nil
+	}
+}
+
+func statRange(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	s := sorted(xs)
+	return number(in.round(in.wp().Sub(s[len(s)-1], s[0]))), nil
+}
+
+// statPercentile interpolates linearly between the closest ranks, like
+// PERCENTILE.INC of the spreadsheets, p is from 0 to 100.
+func statPercentile(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	p, err := in.toFloat(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if p.Sign() < 0 || p.Cmp(big.NewFloat(100)) > 0 {
+		return nil, ErrDomain{Value: in.text(args[0])}
+	}
+	s := sorted(xs)
+	h := in.wp().Mul(p, new(big.Float).SetInt64(int64(len(s)-1)))
+	h.Quo(h, big.NewFloat(100))
+	i, _ := h.Int64()
+	if int(i) >= len(s)-1 {
+		return number(in.round(s[len(s)-1])), nil
+	}
+	frac := h.Sub(h, new(big.Float).SetInt64(i))
+	z := in.wp().Sub(s[i+1], s[i])
+	z.Add(s[i], z.Mul(z, frac))
+	return number(in.round(z)), nil
+}
+
+// extreme returns the item that wins the comparisons, as it is.
+func extreme(wins func(c int) bool) func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+	return func(in *Interpreter, data []Var, xs []*big.Float, args []Var) ([]Var, error) {
+		best := data[0]
+		for _, v := range data[1:] {
+			c, err := cmpNumbers(v, best)
+			if err != nil {
+				return nil, err
+			}
+			if wins(c) {
+				best = v
+			}
+		}
+		return []Var{best}, nil
+	}
+}
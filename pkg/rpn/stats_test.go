@@ -0,0 +1,46 @@
+package rpn
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	testEval(t, []evalTest{
+		{"1 2 3 4 sum", "10"},
+		{"10 1 2 3 3 sumn", "10 6"},
+		{"1 2 3 4 mean", "2.5"},
+		{"3 1 2 median", "2"},
+		{"4 1 3 2 median", "2.5"},
+		{"3 1 3 1 2 mode", "1"},
+		{"2 4 4 4 5 5 7 9 pvar", "4"},
+		{"2 4 4 4 5 5 7 9 pstdev", "2"},
+		{"1 2 3 4 var", "1.6666666666666666666"},
+		{"1 3 stdev", "1.4142135623730950488"},
+		{"5 1 9 range", "8"},
+		{"1 2 3 4 90 percentile", "3.7"},
+		{"1 2 3 4 0 percentile", "1"},
+		{"1 2 3 4 100 percentile", "4"},
+		{"9 1 2 3 4 90 4 percentilen", "9 3.7"},
+		{"1 2 3 count", "3"},
+		{"count", "0"},
+		{"7 5 1/2 3 3 minn", "7 1/2"},
+		{"7 5 1/2 3 3 maxn", "7 5"},
+		{"1 5 max 3 2 min", "5 2"},
+	})
+}
+
+func TestStatsErrors(t *testing.T) {
+	for _, line := range []string{
+		"sum",
+		"1 var",
+		"1 2 3 sumn",
+		"1 2 0 sumn",
+		"1 2 0 minn",
+		"1 2 101 percentile",
+		"1 2 -1 percentile",
+		`1 "a" sum`,
+		"1 2 -1 sumn",
+	} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
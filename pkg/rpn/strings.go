@@ -0,0 +1,133 @@
+package rpn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lex splits a line into lexemes at white space and around brackets, keeping
+// quoted strings like "hello, world\n" whole.
+func Lex(line string) ([]string, error) {
+	var lex []string
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		start := i
+		if line[i] == '[' || line[i] == ']' {
+			lex = append(lex, line[i:i+1])
+			i++
+			continue
+		}
+		if line[i] == '"' {
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, ErrSyntax{Op: line[start:], Pos: len(lex), Msg: "unterminated string"}
+			}
+		}
+		for i < len(line) {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			if unicode.IsSpace(r) || r == '[' || r == ']' {
+				break
+			}
+			i += size
+		}
+		lex = append(lex, line[start:i])
+	}
+	return lex, nil
+}
+
+func str(s string) []Var {
+	return []Var{{Type: String, B: []byte(s)}}
+}
+
+// toString returns the text of a String.
+func toString(v Var) (string, error) {
+	if v.Type != String {
+		return "", ErrType{Want: String, Got: v.Type}
+	}
+	return string(v.B), nil
+}
+
+// text returns the text of a String, or an item formatted in the current
+// display mode.
+func (in *Interpreter) text(v Var) string {
+	if v.Type == String {
+		return string(v.B)
+	}
+	s, _ := in.formatItem(v)
+	return s
+}
+
+// verbs returns the verbs of a fmt format, like "d" and "f" for "%d %.2f%%".
+// Widths and precisions from arguments, like %*d, and argument indexes, like
+// %[1]d, are rejected, each verb taking the next item.
+func verbs(format string) ([]rune, error) {
+	var vs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0; i++ {
+		}
+		if i < len(format) && (format[i] == '*' || format[i] == '[') {
+			return nil, ErrDomain{Value: strconv.Quote(format)}
+		}
+		if i < len(format) && format[i] != '%' {
+			r, size := utf8.DecodeRuneInString(format[i:])
+			vs = append(vs, r)
+			i += size - 1
+		}
+	}
+	return vs, nil
+}
+
+// sprintf formats args with format, converting each of them to what its verb
+// expects: integers for %d and %x, floats for %f and %e, text for %s.
+func (in *Interpreter) sprintf(format string, args []Var) (string, error) {
+	vs, err := verbs(format)
+	if err != nil {
+		return "", err
+	}
+	values := make([]interface{}, len(args))
+	for j, v := range vs {
+		a := args[j]
+		switch {
+		case strings.ContainsRune("xX", v) && a.Type == String:
+			values[j] = a.B
+		case strings.ContainsRune("bdoOxXcU", v):
+			n, err := toInt(a)
+			if err != nil {
+				return "", err
+			}
+			values[j] = n
+			if v == 'c' || v == 'U' {
+				values[j] = rune(n.Int64())
+			}
+		case strings.ContainsRune("eEfFgG", v):
+			f, err := in.toFloat(a)
+			if err != nil {
+				return "", err
+			}
+			values[j] = f
+		default:
+			values[j] = in.text(a)
+		}
+	}
+	return fmt.Sprintf(format, values...), nil
+}
+
+// parseString parses a quoted string lexeme.
+func parseString(s string) (Var, bool) {
+	u, err := strconv.Unquote(s)
+	return Var{Type: String, B: []byte(u)}, err == nil
+}
@@ -0,0 +1,59 @@
+package rpn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	testEval(t, []evalTest{
+		{`"ab" "cd" concat`, `"abcd"`},
+		{`"héllo" len`, "5"},
+		{`"hello" 1 3 substr`, `"ell"`},
+		{`"abc" upper`, `"ABC"`},
+		{`"ABC" lower`, `"abc"`},
+		{`"a,b,c" "," split`, `"a" "b" "c" 3`},
+		{`"hello" "ll" find`, "2"},
+		{`"hello" "z" find`, "-1"},
+		{`"a-b-c" "-" "+" replace`, `"a+b+c"`},
+		{`"42" str>num`, "42"},
+		{`255 num>str`, `"255"`},
+		{`"a" "b" 2 "," join`, `"a,b"`},
+		{`3.14159 "%.2f" format`, `"3.14"`},
+		{`"with space" "\t"`, `"with space" "\t"`},
+	})
+}
+
+func TestPrint(t *testing.T) {
+	in := newTest()
+	var out bytes.Buffer
+	in.Out = &out
+	if err := in.Eval(`"hi there" print 1`); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hi there\n" || show(in) != "1" {
+		t.Errorf("print wrote %q and left %s", out.String(), show(in))
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	for _, line := range []string{`"x" 3 +`, `"abc`, `"a" 5 9 substr`, `"x" str>num`,
+		`3 4 "%*d" format`, `1 2 "%[2]d %[1]d" format`, `3 4 5 "%.*f" format`} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
+
+func TestFormatArgs(t *testing.T) {
+	for _, line := range []string{`3 4 "%*d" format`, `1 2 "%[2]d %[1]d" format`} {
+		in := newTest()
+		if err := in.Eval(line); !errors.As(err, new(ErrDomain)) {
+			t.Errorf("%q gave %v, want ErrDomain", line, err)
+		}
+		if show(in) != "" {
+			t.Errorf("%q left %s", line, show(in))
+		}
+	}
+}
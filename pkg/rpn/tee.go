@@ -0,0 +1,65 @@
+package rpn
+
+import (
+	"io"
+	"os"
+)
+
+// tee starts copying the output to the end of the file name, in place of the
+// file of an earlier tee.
+func (in *Interpreter) tee(name string) error {
+	f, err := os.OpenFile(expandHome(name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return ErrFile{Name: name, Err: err}
+	}
+	in.setTee(f)
+	return nil
+}
+
+// untee stops copying the output.
+func (in *Interpreter) untee() {
+	in.setTee(nil)
+}
+
+// setTee replaces the file of tee with f. The file of the line's start is
+// kept open, for Eval to restore when the line fails, and the ones opened
+// by the line are closed.
+func (in *Interpreter) setTee(f *os.File) {
+	if in.teeFile != nil && in.teeFile != in.teeLine {
+		in.teeFile.Close()
+	}
+	in.teeFile = f
+}
+
+// endTee closes the file of tee that a line replaced when it succeeds, or
+// the one it opened and goes back to the one of its start when it fails.
+func (in *Interpreter) endTee(failed bool) {
+	switch {
+	case in.teeFile == in.teeLine:
+	case failed:
+		in.setTee(in.teeLine)
+	case in.teeLine != nil:
+		in.teeLine.Close()
+	}
+	in.teeLine = nil
+}
+
+// Close stops copying the output and closes the file of the tee word, which
+// an interpreter otherwise holds open until the untee word.
+func (in *Interpreter) Close() error {
+	f := in.teeFile
+	in.teeFile = nil
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+// Tee returns out, or a writer that also copies to the file of the tee word
+// while there is one.
+func (in *Interpreter) Tee(out io.Writer) io.Writer {
+	if in.teeFile == nil {
+		return out
+	}
+	return io.MultiWriter(out, in.teeFile)
+}
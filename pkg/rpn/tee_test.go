@@ -0,0 +1,53 @@
+package rpn
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	in := evalLines(t,
+		"tee "+strconv.Quote(a), `"one" print`,
+		"tee "+strconv.Quote(b), `"two" print`)
+	if err := in.Eval("tee " + strconv.Quote(a) + " nope"); err == nil {
+		t.Fatal("an unknown word didn't fail")
+	}
+	if err := in.Eval("untee nope"); err == nil {
+		t.Fatal("an unknown word didn't fail")
+	}
+	for _, line := range []string{`"three" print`, "untee", `"four" print`} {
+		if err := in.Eval(line); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+	}
+	for name, want := range map[string]string{a: "one\n", b: "two\nthree\n"} {
+		got, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+}
+
+func TestTeeClose(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.log")
+	in := evalLines(t, "tee "+strconv.Quote(name), `"one" print`)
+	if err := in.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Eval(`"two" print`); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Close(); err != nil {
+		t.Errorf("a second Close: %v", err)
+	}
+	if got, err := ioutil.ReadFile(name); err != nil || string(got) != "one\n" {
+		t.Errorf("got %q, %v, want %q", got, err, "one\n")
+	}
+}
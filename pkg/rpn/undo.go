@@ -0,0 +1,133 @@
+package rpn
+
+import "unsafe"
+
+// DefaultUndoDepth is how many lines new interpreters can undo.
+const DefaultUndoDepth = 100
+
+// maxUndoBytes bounds the estimated memory of all the snapshots together,
+// the oldest snapshots are dropped past it.
+const maxUndoBytes = 64 << 20
+
+// snapshot is a state undo and redo step back and forth to.
+type snapshot struct {
+	stack    []Var
+	vars     map[string]Var
+	keyWords map[string]string
+	mode     string
+	vertical bool
+	bytes    int // estimated size of the stack and variables
+}
+
+// varBytes is the size of an item without what it points to.
+const varBytes = int(unsafe.Sizeof(Var{}))
+
+// itemBytes estimates the memory held by an item, counting the mantissas
+// by their precision.
+func itemBytes(v Var) int {
+	n := varBytes + len(v.V) + len(v.B) + len(v.lit)
+	if v.F != nil {
+		n += int(v.F.Prec() / 8)
+	}
+	if v.I != nil {
+		n += int(v.I.Prec() / 8)
+	}
+	if v.R != nil {
+		n += (v.R.Num().BitLen() + v.R.Denom().BitLen()) / 8
+	}
+	for _, c := range v.Code {
+		n += itemBytes(c)
+	}
+	for _, e := range v.Elems {
+		n += itemBytes(e)
+	}
+	return n
+}
+
+func (in *Interpreter) snapshot() snapshot {
+	keyWords := make(map[string]string, len(in.keyWords))
+	for k, v := range in.keyWords {
+		keyWords[k] = v
+	}
+	s := snapshot{
+		stack:    in.Stack(),
+		vars:     in.Vars(),
+		keyWords: keyWords,
+		mode:     in.Mode,
+		vertical: in.Vertical,
+	}
+	for _, v := range s.stack {
+		s.bytes += itemBytes(v)
+	}
+	for k, v := range s.vars {
+		s.bytes += len(k) + itemBytes(v)
+	}
+	return s
+}
+
+func (in *Interpreter) restore(s snapshot) {
+	in.stack = append([]Var(nil), s.stack...)
+	in.vars = make(map[string]Var, len(s.vars))
+	for k, v := range s.vars {
+		in.vars[k] = v
+	}
+	in.keyWords = make(map[string]string, len(s.keyWords))
+	for k, v := range s.keyWords {
+		in.keyWords[k] = v
+	}
+	in.Mode, in.Vertical = s.mode, s.vertical
+}
+
+// Checkpoint records the stack, variables and display mode for undo, it is
+// meant to be called after each line. Lines that only undo or redo aren't
+// recorded, so the lines undone can be redone until a new one is recorded.
+func (in *Interpreter) Checkpoint() {
+	in.changed = false
+	if in.undone {
+		in.undone = false
+		return
+	}
+	in.history = append(in.history[:in.cur+1], in.snapshot())
+	in.cur = len(in.history) - 1
+	size := 0
+	for _, s := range in.history {
+		size += s.bytes
+	}
+	for len(in.history) > 1 && (len(in.history) > in.UndoDepth+1 || size > maxUndoBytes) {
+		size -= in.history[0].bytes
+		in.history[0] = snapshot{}
+		in.history = in.history[1:]
+		in.cur--
+	}
+	if in.UndoDepth <= 0 {
+		in.history, in.cur = nil, -1
+	}
+}
+
+// undo goes back to the snapshot before the current one, or to the current
+// one when the line changed the state since it was recorded, as in '5 undo'.
+func (in *Interpreter) undo() error {
+	if in.changed && in.cur >= 0 {
+		in.restore(in.history[in.cur])
+		in.changed, in.undone = false, true
+		return nil
+	}
+	if in.cur < 1 {
+		return ErrNoHistory{}
+	}
+	in.cur--
+	in.restore(in.history[in.cur])
+	in.undone = true
+	return nil
+}
+
+// redo goes forward to the snapshot after the current one.
+func (in *Interpreter) redo() error {
+	if in.cur+1 >= len(in.history) {
+		return ErrNoHistory{}
+	}
+	in.cur++
+	in.restore(in.history[in.cur])
+	in.undone = true
+	return nil
+}
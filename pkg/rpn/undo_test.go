@@ -0,0 +1,81 @@
+package rpn
+
+import (
+	"errors"
+	"testing"
+)
+
+// checkpointed evaluates lines recording each one for undo, as the rpn command
+// does, and returns the stack after each of them, or the error.
+func checkpointed(in *Interpreter, lines ...string) []string {
+	var stacks []string
+	for _, line := range lines {
+		if err := in.Eval(line); err != nil {
+			stacks = append(stacks, "error: "+err.Error())
+			continue
+		}
+		in.Checkpoint()
+		stacks = append(stacks, show(in))
+	}
+	return stacks
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"1", "2", "undo"}, "1"},
+		{[]string{"1", "2", "undo", "redo"}, "1 2"},
+		{[]string{"1", "2", "undo", "undo", "redo", "redo"}, "1 2"},
+		{[]string{"1", "hex 2", "undo"}, "1"},
+		{[]string{"1", "5 undo"}, "1"},
+		{[]string{"1", "5 undo", "undo"}, ""},
+		{[]string{"1", "5 6 undo 7"}, "1 7"},
+		{[]string{"1", "undo 5"}, "5"},
+		{[]string{"1", "2", "undo", "3"}, "1 3"},
+		{[]string{"1 x=", "2 x=", "undo", "x"}, "1"},
+	}
+	for _, test := range tests {
+		stacks := checkpointed(newTest(), test.lines...)
+		if got := stacks[len(stacks)-1]; got != test.want {
+			t.Errorf("%q: got %s, want %s", test.lines, got, test.want)
+		}
+	}
+}
+
+func TestUndoErrors(t *testing.T) {
+	in := newTest()
+	checkpointed(in, "1", "2", "undo", "3")
+	var e ErrNoHistory
+	if err := in.Eval("redo"); !errors.As(err, &e) {
+		t.Errorf("redo after a new line: got %v, want ErrNoHistory", err)
+	}
+	if err := newTest().Eval("undo"); !errors.As(err, &e) {
+		t.Errorf("undo of nothing: got %v, want ErrNoHistory", err)
+	}
+}
+
+func TestUndoDepth(t *testing.T) {
+	in := newTest()
+	in.UndoDepth = 2
+	checkpointed(in, "1", "2", "3", "undo", "undo")
+	if got := show(in); got != "1" {
+		t.Errorf("got %s, want 1", got)
+	}
+	if err := in.Eval("undo"); err == nil {
+		t.Errorf("undid more than %d lines", in.UndoDepth)
+	}
+}
+
+func TestUndoBytes(t *testing.T) {
+	in := newTest()
+	big := Var{Type: String, B: make([]byte, maxUndoBytes/2)}
+	for i := 0; i < 3; i++ {
+		in.stack = append(in.stack, big)
+		in.Checkpoint()
+	}
+	if len(in.history) != 1 {
+		t.Errorf("kept %d snapshots of %d MiB and more", len(in.history), maxUndoBytes>>21)
+	}
+}
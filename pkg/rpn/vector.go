@@ -0,0 +1,281 @@
+package rpn
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// A Vector holds Numbers and Rationals, or the rows of a matrix, which are
+// Vectors of the same length. They are written like [1 2 3] and
+// [[1 2] [3 4]], bracket groups of numbers being vectors rather than
+// quotations. Calling one pushes its elements, as the quotation would.
+
+// vectorOf returns the Vector of items parsed between brackets, when they
+// are numbers or rows of numbers of the same length.
+func vectorOf(items []Var) (Var, bool) {
+	if len(items) == 0 {
+		return Var{}, false
+	}
+	rows := items[0].Type == Vector
+	for _, v := range items {
+		switch {
+		case !rows && (v.Type == Number || v.Type == Rational):
+		case rows && v.Type == Vector && !isMatrix(v) && len(v.Elems) == len(items[0].Elems):
+		default:
+			return Var{}, false
+		}
+	}
+	return Var{Type: Vector, Elems: items}, true
+}
+
+// isMatrix tells the Vectors of rows from the Vectors of numbers.
+func isMatrix(v Var) bool {
+	return v.Type == Vector && len(v.Elems) > 0 && v.Elems[0].Type == Vector
+}
+
+// shape returns the length of a vector, or the rows and columns of a matrix,
+// like 2x3.
+func shape(v Var) string {
+	if isMatrix(v) {
+		return fmt.Sprintf("%dx%d", len(v.Elems), len(v.Elems[0].Elems))
+	}
+	if v.Type == Vector {
+		return fmt.Sprint(len(v.Elems))
+	}
+	return v.Type.String()
+}
+
+// joinVector formats a Vector like [[1 2] [3 4]], its numbers with f.
+func joinVector(v Var, f func(Var) string) string {
+	text := make([]string, len(v.Elems))
+	for i, e := range v.Elems {
+		if e.Type == Vector {
+			text[i] = joinVector(e, f)
+		} else {
+			text[i] = f(e)
+		}
+	}
+	return "[" + strings.Join(text, " ") + "]"
+}
+
+// formatRows formats a matrix with a row per line and the columns aligned,
+// for the vertical stack.
+func (in *Interpreter) formatRows(v Var) string {
+	cells := make([][]string, len(v.Elems))
+	width := make([]int, len(v.Elems[0].Elems))
+	for i, row := range v.Elems {
+		cells[i] = make([]string, len(row.Elems))
+		for j, e := range row.Elems {
+			cells[i][j], _ = in.formatItem(e)
+			if len(cells[i][j]) > width[j] {
+				width[j] = len(cells[i][j])
+			}
+		}
+	}
+	lines := make([]string, len(cells))
+	for i, row := range cells {
+		for j := range row {
+			row[j] = strings.Repeat(" ", width[j]-len(row[j])) + row[j]
+		}
+		lines[i] = "[" + strings.Join(row, " ") + "]"
+	}
+	return "[" + strings.Join(lines, "\n ") + "]"
+}
+
+// elementwise applies o to the elements of the Vectors in args, and to the
+// other args as they are, for the arithmetic words.
+func (in *Interpreter) elementwise(o op, args []Var) ([]Var, error) {
+	n := -1
+	for _, a := range args {
+		if a.Type != Vector {
+			continue
+		}
+		if n >= 0 && shape(a) != shape(args[0]) {
+			return nil, ErrShape{A: shape(args[0]), B: shape(a)}
+		}
+		n = len(a.Elems)
+	}
+	if n < 0 {
+		return in.call(o, args)
+	}
+	elems := make([]Var, n)
+	sub := make([]Var, len(args))
+	for i := range elems {
+		for j, a := range args {
+			if a.Type == Vector {
+				sub[j] = a.Elems[i]
+			} else {
+				sub[j] = a
+			}
+		}
+		res, err := in.elementwise(o, sub)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) != 1 {
+			return nil, ErrType{Want: Number, Got: Vector}
+		}
+		if t := res[0].Type; t != Number && t != Rational && t != Vector {
+			return nil, ErrType{Want: Number, Got: t}
+		}
+		elems[i] = res[0]
+	}
+	return []Var{{Type: Vector, Elems: elems}}, nil
+}
+
+// broadcast lists the words applied to each element of vectors.
+var broadcast = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "mod": true, "emod": true, "++": true, "--": true,
+	"pow": true, "**": true, "abs": true, "sqrt": true, "exp": true, "ln": true, "log": true, "fact": true,
+	"sin": true, "cos": true, "tan": true, "asin": true, "acos": true, "atan": true,
+	"sinh": true, "cosh": true, "tanh": true, ">rat": true, ">float": true,
+}
+
+// matrix is a matrix, or a vector as a single row, of exact values.
+type matrix struct {
+	rows  [][]*big.Rat
+	exact bool // all the elements were Rationals
+	flat  bool // it was a vector
+}
+
+// toMatrix returns copies of the exact values of a vector or matrix, which
+// the linear algebra words can change in place.
+func (in *Interpreter) toMatrix(v Var) (matrix, error) {
+	if v.Type != Vector {
+		return matrix{}, ErrType{Want: Vector, Got: v.Type}
+	}
+	m := matrix{exact: true, flat: !isMatrix(v)}
+	rows := v.Elems
+	if m.flat {
+		rows = []Var{v}
+	}
+	for _, row := range rows {
+		r := make([]*big.Rat, len(row.Elems))
+		for j, e := range row.Elems {
+			x, err := toRat(e)
+			if err != nil {
+				return matrix{}, err
+			}
+			r[j] = new(big.Rat).Set(x)
+			m.exact = m.exact && e.Type == Rational
+		}
+		m.rows = append(m.rows, r)
+	}
+	return m, nil
+}
+
+// scalar returns an exact result as a Rational, or as a Number when the
+// operands weren't all Rationals.
+func (in *Interpreter) scalar(x *big.Rat, exact bool) Var {
+	if exact {
+		return Var{Type: Rational, R: x}
+	}
+	return Var{Type: Number, F: in.newFloat().SetRat(x)}
+}
+
+// fromRows returns the Vector of exact rows, or of the single row when flat,
+// with copies of their values.
+func (in *Interpreter) fromRows(rows [][]*big.Rat, exact, flat bool) Var {
+	vs := make([]Var, len(rows))
+	for i, row := range rows {
+		elems := make([]Var, len(row))
+		for j := range row {
+			elems[j] = in.scalar(new(big.Rat).Set(row[j]), exact)
+		}
+		vs[i] = Var{Type: Vector, Elems: elems}
+	}
+	if flat {
+		return vs[0]
+	}
+	return Var{Type: Vector, Elems: vs}
+}
+
+// square returns the rows of a square matrix.
+func (in *Interpreter) square(v Var) (matrix, error) {
+	m, err := in.toMatrix(v)
+	if err != nil {
+		return m, err
+	}
+	if m.flat || len(m.rows) != len(m.rows[0]) {
+		return m, ErrShape{A: shape(v), B: "square"}
+	}
+	return m, nil
+}
+
+// eliminate reduces a to row echelon form by Gaussian elimination, applying
+// the same steps to the rows of b, it returns the determinant of a.
+func eliminate(a, b [][]*big.Rat) *big.Rat {
+	n := len(a)
+	det := big.NewRat(1, 1)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && a[pivot][col].Sign() == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return new(big.Rat)
+		}
+		if pivot != col {
+			a[col], a[pivot] = a[pivot], a[col]
+			b[col], b[pivot] = b[pivot], b[col]
+			det.Neg(det)
+		}
+		det.Mul(det, a[col][col])
+		for r := col + 1; r < n; r++ {
+			f := new(big.Rat).Quo(a[r][col], a[col][col])
+			for j := col; j < n; j++ {
+				a[r][j].Sub(a[r][j], new(big.Rat).Mul(f, a[col][j]))
+			}
+			for j := range b[r] {
+				b[r][j].Sub(b[r][j], new(big.Rat).Mul(f, b[col][j]))
+			}
+		}
+	}
+	return det
+}
+
+// backSubstitute solves the echelon form a x = b, in place of b.
+func backSubstitute(a, b [][]*big.Rat) {
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := range b[i] {
+			for k := i + 1; k < len(a); k++ {
+				b[i][j].Sub(b[i][j], new(big.Rat).Mul(a[i][k], b[k][j]))
+			}
+			b[i][j].Quo(b[i][j], a[i][i])
+		}
+	}
+}
+
+// solve returns x of a x = b, or an error when a is singular.
+func (in *Interpreter) solve(av Var, a, b [][]*big.Rat) ([][]*big.Rat, error) {
+	if eliminate(a, b).Sign() == 0 {
+		return nil, ErrDomain{Value: in.Format(av)}
+	}
+	backSubstitute(a, b)
+	return b, nil
+}
+
+func identity(n int) [][]*big.Rat {
+	rows := make([][]*big.Rat, n)
+	for i := range rows {
+		rows[i] = make([]*big.Rat, n)
+		for j := range rows[i] {
+			rows[i][j] = new(big.Rat)
+		}
+		rows[i][i].SetInt64(1)
+	}
+	return rows
+}
+
+// transpose returns the columns of rows, with copies of their values.
+func transpose(rows [][]*big.Rat) [][]*big.Rat {
+	t := make([][]*big.Rat, len(rows[0]))
+	for j := range t {
+		t[j] = make([]*big.Rat, len(rows))
+		for i := range rows {
+			t[j][i] = new(big.Rat).Set(rows[i][j])
+		}
+	}
+	return t
+}
@@ -0,0 +1,82 @@
+package rpn
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	testEval(t, []evalTest{
+		{"[1 2 3]", "[1 2 3]"},
+		{"[[1 2] [3 4]]", "[[1 2] [3 4]]"},
+		{"[1 2 3] 2 *", "[2 4 6]"},
+		{"2 [1 2 3] -", "[1 0 -1]"},
+		{"[1 2] [3 4] +", "[4 6]"},
+		{"[[1 2] [3 4]] 10 *", "[[10 20] [30 40]]"},
+		{"[1 4 9] sqrt", "[1 2 3]"},
+		{"[1/2 1/3] [1/2 2/3] +", "[1 1]"},
+		{"[1 2 3] [4 5 6] dot", "32"},
+		{"[1 0 0] [0 1 0] cross", "[0 0 1]"},
+		{"[[1 2] [3 4]] transpose", "[[1 3] [2 4]]"},
+		{"[1 2] transpose", "[[1] [2]]"},
+		{"[[1 2] [3 4]] det", "-2"},
+		{"[[2 0] [0 4]] inv", "[[0.5 0] [0 0.25]]"},
+		{"[[1 2] [3 4]] [[5 6] [7 8]] matmul", "[[19 22] [43 50]]"},
+		{"[[1 2] [3 4]] [1 1] matmul", "[3 7]"},
+		{"[[2 1] [1 3]] [3 5] solve", "[0.8 1.4]"},
+		{"[3 4] norm", "5"},
+		{"2 identity", "[[1 0] [0 1]]"},
+		{"[1 2] dup", "[1 2] [1 2]"},
+	})
+}
+
+func TestVectorErrors(t *testing.T) {
+	for _, line := range []string{
+		"[1 2] [1 2 3] +",
+		"[1 2] [1 2 3] dot",
+		"[1 2] [3 4] cross",
+		"[[1 2] [3 4] [5 6]] det",
+		"[[1 2] [2 4]] inv",
+		"[[1 2] [2 4]] [1 1] solve",
+		"[[1 2] [3 4]] [1 2 3] matmul",
+		"0 identity",
+		`[1 2] "a" +`,
+	} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
+
+func TestPrintVectors(t *testing.T) {
+	in := evalLines(t, "[1 2] [[1 2] [3 4]]")
+	var b bytes.Buffer
+	in.PrintStack(&b)
+	if got, want := b.String(), "[ [1 2],[[1 2] [3 4]],\b ]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	b.Reset()
+	in.Vertical = true
+	in.PrintStack(&b)
+	if got, want := b.String(), "[ [1 2]\n[[1 2]\n [3 4]]\n\b ]"; got != want {
+		t.Errorf("vertical: got %q, want %q", got, want)
+	}
+}
+
+func TestMatrixOperandsKept(t *testing.T) {
+	testEval(t, []evalTest{
+		{"rat [[1 2] [3 4]] dup det drop", "[[1 2] [3 4]]"},
+		{"rat [[1 2] [3 4]] dup inv drop", "[[1 2] [3 4]]"},
+		{"rat [[2 1] [1 3]] dup [3 5] solve drop", "[[2 1] [1 3]]"},
+		{"rat [[1 2] [3 4]] dup transpose swap inv drop", "[[1 3] [2 4]]"},
+		{"rat [[1 2] [3 4]] m= m det m inv m [1 1] solve clr m", "[[1 2] [3 4]]"},
+		{"[[1 2] [3 4]] m= m det m inv m [1 1] solve clr m", "[[1 2] [3 4]]"},
+	})
+	in := evalLines(t, "rat [[1 2] [2 4]] m=")
+	if err := in.Eval("m [1 1] solve"); err == nil {
+		t.Fatal("a singular matrix didn't fail")
+	}
+	if err := in.Eval("m"); err != nil || show(in) != "[[1 2] [2 4]]" {
+		t.Errorf("m is %s, %v", show(in), err)
+	}
+}
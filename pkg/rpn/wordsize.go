@@ -0,0 +1,168 @@
+package rpn
+
+import "math/big"
+
+// maxWordSize bounds the word size set by ws.
+const maxWordSize = 1 << 16
+
+// wrapOp is the integer version of a word whose results wrap to the word
+// size. Its first operands are wrapped to the word size and the others, like
+// the count of a shift, are taken as they are.
+type wrapOp struct {
+	op
+	n int // operands wrapped to the word size
+}
+
+// wraps holds the words that compute on integers of the word size, when
+// there is one, exactly, before their results wrap.
+var wraps = map[string]wrapOp{
+	"+": {integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Add(a, b), nil }), 2},
+	"-": {integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Sub(a, b), nil }), 2},
+	"*": {integer2(func(a, b *big.Int) (*big.Int, error) { return new(big.Int).Mul(a, b), nil }), 2},
+	"/": {integer2(func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		return new(big.Int).Quo(a, b), nil
+	}), 2},
+	"%":    {integer2(intRemainder(truncMod)), 2},
+	"mod":  {integer2(intRemainder(floorMod)), 2},
+	"emod": {integer2(intRemainder(euclidMod)), 2},
+	"++":   {integer1(func(a *big.Int) (*big.Int, error) { return new(big.Int).Add(a, big.NewInt(1)), nil }), 1},
+	"--":   {integer1(func(a *big.Int) (*big.Int, error) { return new(big.Int).Sub(a, big.NewInt(1)), nil }), 1},
+	"abs":  {integer1(func(a *big.Int) (*big.Int, error) { return new(big.Int).Abs(a), nil }), 1},
+	"&":    {ops["&"], 2},
+	"|":    {ops["|"], 2},
+	"^":    {ops["^"], 2},
+	"~":    {ops["~"], 1},
+	"<<":   {op{2, shiftLeft}, 1},
+	">>":   {ops[">>"], 1},
+	"pow":  {op{2, wordPow}, 1},
+	"**":   {op{2, wordPow}, 1},
+}
+
+// wrapped returns the version of o wrapping to the word size, which falls
+// back on o, wrapping its results, for the operands that aren't Numbers or
+// Rationals, like complex numbers.
+func (w wrapOp) wrapped(o op) op {
+	return op{o.arity, func(in *Interpreter, args []Var) ([]Var, error) {
+		xs := make([]Var, len(args))
+		for i, a := range args {
+			if a.Type != Number && a.Type != Rational {
+				res, err := o.fn(in, args)
+				if err != nil {
+					return nil, err
+				}
+				return in.wrap(res), nil
+			}
+			xs[i] = a
+			if i < w.n {
+				x, err := toInt(a)
+				if err != nil {
+					return nil, err
+				}
+				xs[i] = number(new(big.Float).SetInt(in.wrapInt(x)))[0]
+			}
+		}
+		res, err := w.fn(in, xs)
+		if err != nil {
+			return nil, err
+		}
+		return in.wrap(res), nil
+	}}
+}
+
+// intRemainder returns the remainder of a / b of a kind, as remainder does.
+func intRemainder(kind int) func(a, b *big.Int) (*big.Int, error) {
+	return func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() == 0 {
+			return nil, ErrDivisionByZero{}
+		}
+		r := new(big.Int).Rem(a, b)
+		switch {
+		case kind == floorMod && r.Sign()*b.Sign() < 0:
+			r.Add(r, b)
+		case kind == euclidMod:
+			r.Mod(a, b)
+		}
+		return r, nil
+	}
+}
+
+// shiftLeft shifts by at most the word size, past which all the bits are
+// gone anyway.
+func shiftLeft(in *Interpreter, args []Var) ([]Var, error) {
+	a, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if b.Sign() < 0 {
+		return nil, ErrDomain{Value: b.String()}
+	}
+	if b.Cmp(big.NewInt(int64(in.WordSize))) > 0 {
+		b.SetUint64(uint64(in.WordSize))
+	}
+	return in.integer(new(big.Int).Lsh(a, uint(b.Uint64()))), nil
+}
+
+// wordPow raises a to the integer power b modulo 2**WordSize, and truncates
+// the fractions of negative powers like the other words do.
+func wordPow(in *Interpreter, args []Var) ([]Var, error) {
+	a, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if b.Sign() < 0 {
+		switch {
+		case a.Sign() == 0:
+			return nil, ErrDivisionByZero{}
+		case a.CmpAbs(big.NewInt(1)) != 0:
+			return in.integer(new(big.Int)), nil
+		}
+		b.Neg(b)
+	}
+	m := new(big.Int).Lsh(big.NewInt(1), in.WordSize)
+	return in.integer(new(big.Int).Exp(in.unsigned(a), b, m)), nil
+}
+
+// wrap truncates the Numbers and Rationals of res to integers and wraps them
+// to the word size, like the registers of a programmer's calculator.
+func (in *Interpreter) wrap(res []Var) []Var {
+	for i, v := range res {
+		switch v.Type {
+		case Number, Rational:
+			if x, err := toInt(v); err == nil {
+				res[i] = in.integer(in.wrapInt(x))[0]
+			}
+		case Vector:
+			elems := append([]Var(nil), v.Elems...)
+			res[i].Elems = in.wrap(elems)
+		}
+	}
+	return res
+}
+
+// wrapInt returns x modulo 2**WordSize, from -2**(WordSize-1) when signed.
+func (in *Interpreter) wrapInt(x *big.Int) *big.Int {
+	z := in.unsigned(x)
+	if in.Signed && z.Bit(int(in.WordSize)-1) == 1 {
+		z.Sub(z, new(big.Int).Lsh(big.NewInt(1), in.WordSize))
+	}
+	return z
+}
+
+// unsigned returns the WordSize bits of x, which are the two's complement
+// of negative numbers.
+func (in *Interpreter) unsigned(x *big.Int) *big.Int {
+	mask := new(big.Int).Lsh(big.NewInt(1), in.WordSize)
+	mask.Sub(mask, big.NewInt(1))
+	return new(big.Int).And(x, mask)
+}
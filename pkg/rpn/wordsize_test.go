@@ -0,0 +1,45 @@
+package rpn
+
+import "testing"
+
+func TestWordSize(t *testing.T) {
+	testEval(t, []evalTest{
+		{"8 ws 255 1 +", "0"},
+		{"8 ws 0 1 -", "255"},
+		{"8 ws signed 127 1 +", "-128"},
+		{"8 ws 0 ~", "255"},
+		{"8 ws signed 1 7 <<", "-128"},
+		{"8 ws signed 1 7 << hex", "0x80"},
+		{"8 ws 300", "44"},
+		{"8 ws signed 200", "-56"},
+		{"8 ws 300 hex", "0x2c"},
+		{"8 ws 300 1 +", "45"},
+		{"8 ws 1.5", "1.5"},
+		{"8 ws 7.9 2 /", "3"},
+		{"8 ws signed 0xff 1 >>", "-1"},
+		{"8 ws 0xff 1 >>", "127"},
+		{"8 ws 1 300 <<", "0"},
+		{"8 ws signed -7 3 %", "-1"},
+		{"8 ws signed -7 3 mod", "2"},
+		{"8 ws signed -7 -3 emod", "2"},
+		{"8 ws signed 200 abs", "56"},
+		{"8 ws 2 -1 pow", "0"},
+		{"8 ws [1 300] 1 +", "[2 45]"},
+		{"16 ws 3+4i 1 +", "4+4i"},
+		{"64 ws 0xffffffffffffffff 3 * hex", "0xfffffffffffffffd"},
+		{"64 ws 3 41 pow hex", "0xfa2a1cf67b5fb863"},
+		{"64 ws 2 64 pow 1 -", "18446744073709551615"},
+		{"64 ws signed 0x7fffffffffffffff 1 +", "-9223372036854775808"},
+		{"128 ws 1 127 << 1 - 3 * hex", "0x7ffffffffffffffffffffffffffffffd"},
+		{"rat 8 ws 1 3 / 300 +", "44"},
+		{"8 ws 0 ws 300", "300"},
+	})
+}
+
+func TestWordSizeErrors(t *testing.T) {
+	for _, line := range []string{"-1 ws", "65537 ws", "8 ws 1 0 /", "8 ws 1 0 %", "8 ws 1 -1 <<", "8 ws 0 -1 pow"} {
+		if err := newTest().Eval(line); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
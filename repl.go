@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/chzyer/readline"
+	"github.com/f01c33/rpn/pkg/rpn"
+)
+
+// completer completes the word under the cursor with the keywords, variables
+// and macros of the interpreter.
+type completer struct {
+	calc *rpn.Interpreter
+}
+
+func (c completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && !unicode.IsSpace(line[start-1]) && line[start-1] != '[' && line[start-1] != ']' {
+		start--
+	}
+	word := string(line[start:pos])
+	var suffixes [][]rune
+	for _, name := range c.names() {
+		if strings.HasPrefix(name, word) {
+			suffixes = append(suffixes, []rune(name[len(word):]+" "))
+		}
+	}
+	return suffixes, pos - start
+}
+
+// names returns the keywords, variables and macros, sorted and without
+// duplicates.
+func (c completer) names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range c.calc.Keywords() {
+		add(name)
+	}
+	for name := range c.calc.Vars() {
+		add(name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// historyFile returns ~/.rpn_history, or "" when there is no home directory.
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".rpn_history")
+}
+
+// undoKey is ^_, which is also what most terminals send for ^/.
+const undoKey = 0x1f
+
+// repl reads the lines from the terminal with line editing, history, reverse
+// search (^R) and tab completion. The undo key evaluates undo, dropping the
+// line being edited.
+func repl(calc *rpn.Interpreter, out io.Writer) error {
+	undo := false
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 "> ",
+		HistoryFile:            historyFile(),
+		DisableAutoSaveHistory: true,
+		AutoComplete:           completer{calc},
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "exit",
+		FuncFilterInputRune: func(r rune) (rune, bool) {
+			if r == undoKey {
+				undo = true
+				return readline.CharEnter, true
+			}
+			return r, true
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+	p := &printer{out: out}
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if undo {
+			line, undo = "undo", false
+		} else if err := rl.SaveHistory(line); err != nil {
+			return err
+		}
+		evalLine(calc, line, p)
+		if calc.Exit {
+			return nil
+		}
+	}
+}
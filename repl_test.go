@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/f01c33/rpn/pkg/rpn"
+)
+
+func TestCompleterNames(t *testing.T) {
+	calc := rpn.New()
+	for _, line := range []string{"1 zz=", "2 aa=", "macro sq dup *", "3 sqrt=", "4 zzz="} {
+		if err := calc.Eval(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := completer{calc}.names()
+	if !sort.StringsAreSorted(names) {
+		t.Error("the names aren't sorted")
+	}
+	count := make(map[string]int)
+	for _, name := range names {
+		count[name]++
+	}
+	for _, name := range []string{"zz", "aa", "sq", "sqrt", "zzz", "dup"} {
+		if count[name] != 1 {
+			t.Errorf("%s is listed %d times", name, count[name])
+		}
+	}
+}
+
+func TestCompleterDo(t *testing.T) {
+	calc := rpn.New()
+	if err := calc.Eval("1 sqfoot="); err != nil {
+		t.Fatal(err)
+	}
+	line := []rune("2 [ sq")
+	got, n := completer{calc}.Do(line, len(line))
+	want := [][]rune{[]rune("foot "), []rune("rt ")}
+	if n != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q %d, want %q 2", got, n, want)
+	}
+}